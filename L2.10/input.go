@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"L2.10/sorter"
+)
+
+// Имя входа, означающее стандартный ввод
+const stdinName = "-"
+
+// Возвращает список входных файлов: из аргументов или из файла со списком,
+// где имена разделены NUL, как у GNU sort --files0-from. Без файлов читаем stdin
+func inputNames(args []string, files0From string) ([]string, error) {
+	if files0From == "" {
+		if len(args) == 0 {
+			return []string{stdinName}, nil
+		}
+		return args, nil
+	}
+
+	if len(args) > 0 {
+		return nil, errorf(msgFilesWithFiles0From)
+	}
+
+	var data []byte
+	var err error
+	if files0From == stdinName {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(files0From)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range bytes.SplitSeq(data, []byte{0}) {
+		if len(name) == 0 {
+			continue // пустой хвост после последнего NUL
+		}
+		if string(name) == stdinName && files0From == stdinName {
+			return nil, errorf(msgStdinTwice)
+		}
+		names = append(names, string(name))
+	}
+	if len(names) == 0 {
+		return nil, errorf(msgNoFiles0From)
+	}
+	return names, nil
+}
+
+// inputFile — входной файл, декодированный в UTF-8. Открывается при первом чтении
+// и закрывается, когда дочитан, чтобы при длинном списке файлов не держать открытыми все
+type inputFile struct {
+	name     string
+	encoding sorter.Encoding // кодировка файла, по умолчанию по BOM
+	file     io.Closer       // открытый файл, nil — еще не открыт или уже закрыт
+	dec      *sorter.Decoder // декодер файла, nil — файл еще не открывался
+	done     bool            // файл дочитан до конца
+}
+
+// Готовит входы для сортировки, "-" — стандартный ввод. Файлы открываются по мере чтения
+func newInputFiles(names []string, enc sorter.Encoding) []*inputFile {
+	files := make([]*inputFile, len(names))
+	for i, name := range names {
+		files[i] = &inputFile{name: name, encoding: enc}
+	}
+	return files
+}
+
+// Открывает файл и определяет его кодировку
+func (f *inputFile) open() error {
+	var file io.ReadCloser = io.NopCloser(os.Stdin)
+	if f.name != stdinName {
+		var err error
+		if file, err = os.Open(f.name); err != nil {
+			return err
+		}
+	}
+	d, err := sorter.NewDecoder(file, f.encoding)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.dec = file, d
+	return nil
+}
+
+func (f *inputFile) Read(p []byte) (int, error) {
+	if f.done {
+		return 0, io.EOF
+	}
+	if f.dec == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.dec.Read(p)
+	if err == io.EOF {
+		f.done = true
+		f.Close()
+	}
+	return n, err
+}
+
+// Кодировка и BOM файла, чтобы писать результат в них же. Если файл еще не открыт, открывает его
+func (f *inputFile) sourceEncoding() (sorter.Encoding, bool, error) {
+	if f.dec == nil {
+		if err := f.open(); err != nil {
+			return sorter.EncodingUTF8, false, err
+		}
+	}
+	return f.dec.Encoding(), f.dec.BOM(), nil
+}
+
+// Close закрывает файл, если он открыт
+func (f *inputFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Открывает каждый вход отдельно и декодирует его в UTF-8.
+// Возвращает функцию, закрывающую открытые файлы
+func openInputs(names []string, enc sorter.Encoding) ([]io.Reader, func(), error) {
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	inputs := make([]io.Reader, len(names))
+	for i, name := range names {
+		f := os.Stdin
+		if name != stdinName {
+			var err error
+			if f, err = os.Open(name); err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			files = append(files, f)
+		}
+		d, err := sorter.NewDecoder(f, enc)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		inputs[i] = d
+	}
+	return inputs, closeAll, nil
+}
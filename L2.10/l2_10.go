@@ -1,401 +1,148 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"slices"
+	"os/signal"
+	"runtime"
 	"strconv"
 	"strings"
-)
-
-// Months содержит соответствие месяца и его номера
-var Months = map[string]int{
-	"Jan": 1,
-	"Feb": 2,
-	"Mar": 3,
-	"Apr": 4,
-	"May": 5,
-	"Jun": 6,
-	"Jul": 7,
-	"Aug": 8,
-	"Sep": 9,
-	"Oct": 10,
-	"Nov": 11,
-	"Dec": 12,
-}
-
-// Sorter сортирует строки по заданным флагам
-type Sorter struct {
-	Column        int
-	Numeric       bool
-	Reverse       bool
-	RemoveTBlanks bool
-	Unique        bool
-	CheckSort     bool
-	HumanReadable bool
-	MonthCheck    bool
-	SortType      bool
-	Err           error
-}
-
-// Хранит строку и ее числовое значение, если возможно
-type sortableLine struct {
-	line   string
-	keyInt int
-	keyStr string
-}
-
-// Преобразовывает строки в sortableLine, находя ключи
-func (s Sorter) buildSortableLines(lines []string) ([]sortableLine, error) {
-	res := make([]sortableLine, len(lines))
-
-	for i, line := range lines {
-		var keyInt int
-		var keyStr string
-
-		// Берем нужную колонку
-		colText, err := getColumn(line, s.Column)
-		if err != nil {
-			return nil, err
-		}
-
-		// Если нужно, убираем хвостовые пробелы сразу
-		if s.RemoveTBlanks {
-			colText = strings.TrimRight(colText, " \t")
-		}
+	_ "time/tzdata" // база поясов для --timezone, даже если в системе ее нет
+	"unicode/utf8"
 
-		if s.Numeric {
-			keyInt, err = strconv.Atoi(colText)
-			if err != nil {
-				return nil, fmt.Errorf("ошибка преобразования '%s': %w", colText, err)
-			}
-		} else if s.HumanReadable {
-			keyInt, err = toHumanFormat(colText)
-			if err != nil {
-				return nil, fmt.Errorf("ошибка преобразования '%s': %w", colText, err)
-			}
-		} else if s.MonthCheck {
-			if !isMonth(colText) {
-				return nil, fmt.Errorf("строка не месяц '%s'", colText)
-			}
-			keyInt = Months[colText]
-		} else {
-			keyStr = colText // для обычных строк берём именно колонку
-		}
+	"L2.10/sorter"
+)
 
-		res[i] = sortableLine{
-			line:   line,
-			keyInt: keyInt,
-			keyStr: keyStr,
-		}
+// Читает входы, сортирует и пишет результат в out. Заголовок пропускается
+// в каждом файле, в ошибках ключей — имя файла и номер строки в нем
+func sortTo(ctx context.Context, s *sorter.Sorter, files []*inputFile, out io.Writer) error {
+	inputs := make([]io.Reader, len(files))
+	names := make([]string, len(files))
+	for i, f := range files {
+		inputs[i], names[i] = f, f.name
 	}
-
-	return res, nil
-}
-
-// Sort делает сортировку выбранным методом
-func (s *Sorter) Sort(lines []string) error {
-	if s.SortType {
-		return s.SortA(lines)
+	if err := s.SortInputs(ctx, inputs, names, out); err != nil { //Если сортировка выкинула ошибку
+		return errorf(msgSortFailed, err)
 	}
-	return s.SortB(lines)
+	return nil
 }
 
-// SortA делает сортировку полученных строк, с помощью buildSortableLines
-func (s *Sorter) SortA(lines []string) error {
-	s.Err = nil
-
-	SLines, err := s.buildSortableLines(lines)
+// Проверяет, что файлы отсортированы, и возвращает код выхода как GNU sort -c:
+// 0 — отсортированы, 1 — нет, 2 — ошибка. Нарушение порядка пишется в stderr, если не quiet
+func checkFiles(ctx context.Context, s *sorter.Sorter, names []string, enc sorter.Encoding, quiet bool) int {
+	inputs, closeInputs, err := openInputs(names, enc)
 	if err != nil {
-		s.Err = err
-		return err
+		log.Print(err)
+		return 2
 	}
+	defer closeInputs()
 
-	slices.SortFunc(SLines, s.compareLinesA)
-
-	for i := range lines {
-		lines[i] = SLines[i].line
-	}
-
-	return s.Err
-}
-
-// SortB делает сортировку полученных строк
-func (s *Sorter) SortB(lines []string) error {
-	s.Err = nil
-	slices.SortFunc(lines, s.compareLinesB)
-	return s.Err
-}
-
-// Функция сравнения для sortableLine
-func (s *Sorter) compareLinesA(a, b sortableLine) int {
-	if s.Numeric || s.HumanReadable || s.MonthCheck {
-		return compareInts(a.keyInt, b.keyInt, s.Reverse)
-	}
-
-	aStr := a.keyStr
-	bStr := b.keyStr
-
-	if s.RemoveTBlanks {
-		aStr = strings.TrimRight(aStr, " \t")
-		bStr = strings.TrimRight(bStr, " \t")
-	}
-
-	return compareStrings(aStr, bStr, s.Reverse)
-}
-
-// Функция сравнения двух строк по заданным параметрам
-func (s *Sorter) compareLinesB(a, b string) int {
-	if s.Err != nil {
+	err = s.Check(ctx, inputs, names)
+	var disorder *sorter.DisorderError
+	switch {
+	case err == nil:
 		return 0
-	}
-
-	// Убираем хвостовые пробелы по флагу
-	if s.RemoveTBlanks {
-		a = strings.TrimRight(a, " \t")
-		b = strings.TrimRight(b, " \t")
-	}
-
-	// Берем нужную колонку
-	var err error
-	va, err := getColumn(a, s.Column)
-	if err != nil {
-		s.Err = err
-		return 0
-	}
-	vb, err := getColumn(b, s.Column)
-	if err != nil {
-		s.Err = err
-		return 0
-	}
-
-	// Сортируем как месяца
-	if s.MonthCheck {
-		if !isMonth(va) {
-			s.Err = fmt.Errorf("строка не месяц '%s'", va)
-			return 0
-		}
-		if !isMonth(vb) {
-			s.Err = fmt.Errorf("строка не месяц '%s'", vb)
-			return 0
-		}
-
-		return compareMonth(va, vb, s.Reverse)
-	}
-	// Сортируем по числовому значению с учётом суффиксов
-	if s.HumanReadable {
-		var err error
-		var aInt, bInt int
-
-		aInt, err = toHumanFormat(va)
-		if err != nil {
-			s.Err = fmt.Errorf("ошибка преобразования '%s': %w", va, err)
-			return 0
-		}
-
-		bInt, err = toHumanFormat(vb)
-		if err != nil {
-			s.Err = fmt.Errorf("ошибка преобразования '%s': %w", vb, err)
-			return 0
-		}
-
-		return compareInts(aInt, bInt, s.Reverse)
-	}
-	// Сортируем по числовому значению
-	if s.Numeric {
-		aInt, err := strconv.Atoi(va)
-		if err != nil {
-			s.Err = fmt.Errorf("ошибка преобразования '%s': %w", va, err)
-			return 0
-		}
-
-		bInt, err := strconv.Atoi(vb)
-		if err != nil {
-			s.Err = fmt.Errorf("ошибка преобразования '%s': %w", vb, err)
-			return 0
+	case errors.As(err, &disorder):
+		if !quiet {
+			fmt.Fprintln(os.Stderr, disorder)
 		}
-
-		return compareInts(aInt, bInt, s.Reverse)
+		return 1
+	default:
+		log.Print(err)
+		return 2
 	}
-
-	return compareStrings(va, vb, s.Reverse)
-}
-
-// Проверяет, отсортирован ли массив строк в соответствии с compareLines
-func (s *Sorter) isSorted(lines []string) bool {
-	return slices.IsSortedFunc(lines, s.compareLinesB)
 }
 
-// Берет колонку из строки по номеру разделитель - табуляция
-func getColumn(l string, column int) (string, error) {
-	afterLastTabPos := 0
-	curColumn := 1
-	for i, v := range l {
-		if v == '\t' {
-			if curColumn == column {
-				return l[afterLastTabPos:i], nil
-			}
-			curColumn++
-			afterLastTabPos = i + 1
-		}
+// Сливает отсортированные файлы и пишет результат в outputPath.
+// При keepEncoding результат перекодируется в кодировку первого файла
+func mergeTo(ctx context.Context, s *sorter.Sorter, names []string, outputPath string, enc sorter.Encoding, keepEncoding bool) error {
+	inputs, closeInputs, err := openInputs(names, enc)
+	if err != nil {
+		return err
 	}
+	defer closeInputs()
 
-	if curColumn == column { //Последний столбец
-		return l[afterLastTabPos:], nil
+	out, err := openOutput(outputPath)
+	if err != nil {
+		return err
 	}
-	return "", fmt.Errorf("строка имеет меньше столбцов, чем k=%d: '%s'", column, l)
-}
-
-// Сравнивает строки с флагами
-func compareStrings(va string, vb string, reverse bool) int {
-	if reverse { //Если реверс
-		return strings.Compare(vb, va)
+	var w io.Writer = out
+	if keepEncoding {
+		first := inputs[0].(*sorter.Decoder)
+		w = sorter.NewEncoder(out, first.Encoding(), first.BOM())
 	}
-	return strings.Compare(va, vb)
-}
-
-// Сравнение чисел с реверсом
-func compareInts(a, b int, reverse bool) int {
-	if a == b {
-		return 0
+	if err := s.Merge(ctx, inputs, names, w); err != nil {
+		out.Abort()
+		return errorf(msgMergeFailed, err)
 	}
-	if reverse {
-		if a < b {
-			return 1
-		}
-		return -1
+	if err := out.Commit(); err != nil {
+		return errorf(msgWriteOutput, err)
 	}
-	if a < b {
-		return -1
-	}
-	return 1
-}
-
-// Проверяет является ли строка месяцом
-func isMonth(a string) (ok bool) {
-	_, ok = Months[a]
-	return
-}
-
-// Сравнение строк типа месяц
-func compareMonth(a, b string, reverse bool) int {
-	return compareInts(Months[a], Months[b], reverse)
+	return nil
 }
 
-// Для первода строк вида 2к в строку 2048 уже сразу как число!
-func toHumanFormat(s string) (int, error) {
-	sizeSuff := map[rune]int{
-		'K': 1024,
-		'M': 1024 * 1024,
-		'G': 1024 * 1024 * 1024,
-		'k': 1024,
-		'm': 1024 * 1024,
-		'g': 1024 * 1024 * 1024,
-	}
-
-	multiplier := 1
-	var numPart string
-	var suff rune
-
-	for i, r := range s {
-		if _, ok := sizeSuff[r]; ok {
-			numPart = s[:i]
-			suff = r
-			break
+// Открывает файл для строк из карантина. Без --reject возвращает nil,
+// но при --bad-lines=quarantine файл обязателен
+func openReject(path string, policy sorter.BadLinePolicy) (*output, error) {
+	if path == "" {
+		if policy == sorter.BadLineQuarantine {
+			return nil, errorf(msgRejectRequired)
 		}
+		return nil, nil
 	}
-
-	if suff != 0 {
-		multiplier = sizeSuff[suff]
-	} else {
-		numPart = s
-	}
-
-	num, err := strconv.Atoi(numPart)
-	if err != nil {
-		return 0, err
-	}
-
-	res := num * multiplier
-	return res, nil
+	return openOutput(path)
 }
 
-// Удаляет дубликаты но только в отсортированном массиве где дубликаты стоят рядом
-func removeDuplicatesSorted(lines []string) []string {
-	if len(lines) == 0 {
-		return lines
+// Разбирает разделитель записей, раскрывая экранирование Go: \x00, \x1e, \r\n
+func parseRecordSep(value string) (string, error) {
+	sep, err := strconv.Unquote(`"` + strings.ReplaceAll(value, `"`, `\"`) + `"`)
+	if err != nil || sep == "" {
+		return "", errorf(msgBadRecordSep, value)
 	}
-
-	uniq := []string{lines[0]}
-	prev := lines[0]
-
-	for _, line := range lines[1:] {
-		if line != prev {
-			uniq = append(uniq, line)
-			prev = line
-		}
-	}
-
-	return uniq
+	return sep, nil
 }
 
-// Урезает хвостовые пробелы если надо
-// func cleanTrailingBlanks(lines []string) []string {
-// 	cleaned := make([]string, 0, len(lines))
-// 	for _, l := range lines {
-// 		s := strings.TrimRight(l, " \t") // убираем и хвостовые, и ведущие пробелы
-// 		cleaned = append(cleaned, s)
-// 	}
-// 	return cleaned
-// }
-
-// Читает все непустые строки с файла
-func readLines(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) != "" { // пропускаем пустые строки
-			lines = append(lines, line)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return lines, nil
+// keyFlag собирает повторяющиеся флаги -k в список ключей
+type keyFlag struct {
+	keys *[]sorter.KeySpec
 }
 
-// Выводит массив строк
-func printLines(lines []string) {
-	for _, v := range lines {
-		fmt.Println(v)
+func (f keyFlag) String() string {
+	if f.keys == nil {
+		return ""
+	}
+	specs := make([]string, len(*f.keys))
+	for i, k := range *f.keys {
+		specs[i] = k.String()
 	}
+	return strings.Join(specs, " ")
 }
 
-// Пишет строки в йказанный файл
-func writeLinesToFile(lines []string, filename string) error {
-	file, err := os.Create(filename) // Создаёт файл, перезаписывая
+func (f keyFlag) Set(value string) error {
+	k, err := sorter.ParseKeySpec(value)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	*f.keys = append(*f.keys, k)
+	return nil
+}
 
-	for _, v := range lines {
-		_, err := file.WriteString(v + "\n")
-		if err != nil {
-			return err
+// Локаль сравнения из окружения: LC_ALL, LC_COLLATE, LANG. Незнакомая локаль — C
+func collationFromEnv() string {
+	for _, env := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			if _, err := sorter.ParseCollation(v); err == nil {
+				return v
+			}
+			return "C"
 		}
 	}
-	return nil
+	return "C"
 }
 
 // Разбивает слипшиеся флаги
@@ -403,83 +150,232 @@ func preprocessFlags() {
 	// Слайс для новых флагов
 	var expanded []string
 	for _, arg := range os.Args[1:] {
-		// Аргумент вида -nrbu
-		if strings.HasPrefix(arg, "-") && len(arg) > 2 { // Разбиваем каждый символ в отдельный флаг
-			for _, r := range arg[1:] {
-				expanded = append(expanded, "-"+string(r))
-			}
-		} else { // Обычный флаг
-			expanded = append(expanded, arg)
-		}
+		expanded = append(expanded, expandShortFlags(arg)...)
 	}
 	// Заменяем os.Args на expanded
 	os.Args = append([]string{os.Args[0]}, expanded...)
 }
 
-func main() {
-	preprocessFlags()
+// Разбивает аргумент вида -nrbu на отдельные флаги, а -nrk2 на -n -r -k 2.
+// Если в группе встречается неизвестная буква или аргумент — длинный флаг
+// с одним дефисом, как -count или -si, он остается как есть
+func expandShortFlags(arg string) []string {
+	if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") || len(arg) <= 2 || strings.Contains(arg, "=") {
+		return []string{arg}
+	}
+	if flag.Lookup(arg[1:]) != nil {
+		return []string{arg}
+	}
+
+	var res []string
+	for i, r := range arg[1:] {
+		f := flag.Lookup(string(r))
+		if f == nil {
+			return []string{arg}
+		}
+		res = append(res, "-"+string(r))
+		if !isBoolFlag(f) { // Остаток аргумента — значение флага
+			if rest := arg[1+i+utf8.RuneLen(r):]; rest != "" {
+				res = append(res, rest)
+			}
+			break
+		}
+	}
+	return res
+}
 
-	var s Sorter
-	var resultToFile bool
+// Проверяет, что флаг булевый и не требует значения
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
 
-	flag.IntVar(&s.Column, "k", 1, "column for sort")
+func main() {
+	var s sorter.Sorter
+	var bufferSize string
+	var files0From string
+	var outputPath string
+	var rejectPath string
+	var encoding sorter.Encoding
+	var keepEncoding bool
+	var mergeOnly bool
+	var quietCheck bool
+
+	flag.Var(keyFlag{&s.Keys}, "k", "sort key F[.C][OPTS][,F[.C][OPTS]], NAME[,NAME][:OPTS] with --header or PATH[:OPTS] with --json, e.g. -k 2,2n -k price:n -k .ts; can be repeated")
+	flag.Func("F", "field separator, may be multi-byte (default tab); empty value splits fields on runs of blanks", func(sep string) error {
+		s.Separator = sep
+		s.BlankFields = sep == ""
+		return nil
+	})
 	flag.BoolVar(&s.Numeric, "n", false, "sort string as number")
+	flag.BoolVar(&s.GeneralNumeric, "g", false, "sort string as general number: floats, exponents, inf and nan")
+	flag.BoolVar(&s.DecimalComma, "decimal-comma", false, "numbers use comma as decimal separator: 3,14")
+	flag.StringVar(&s.ThousandsSep, "thousands-sep", "", "thousands separator removed before parsing general numbers")
 	flag.BoolVar(&s.Reverse, "r", false, "reverse sort")
-	flag.BoolVar(&s.Unique, "u", false, "only unique values")
-	flag.BoolVar(&s.RemoveTBlanks, "b", false, "ignore trailing blanks")
-	flag.BoolVar(&s.CheckSort, "c", false, "check sort")
+	flag.BoolVar(&s.Unique, "u", false, "output only the first of lines with equal keys")
+	flag.Func("unique-keep", "with -u keep first, last or the first line by key, e.g. 3,3nr", func(value string) error {
+		var err error
+		s.UniqueKeep, s.UniqueBy, err = sorter.ParseUniquePolicy(value)
+		return err
+	})
+	flag.BoolVar(&s.CountDuplicates, "count", false, "with -u prefix lines by the number of occurrences, like uniq -c")
+	flag.BoolVar(&s.SkipBlanks, "b", false, "ignore leading blanks in sort keys")
+	flag.BoolVar(&s.RemoveTBlanks, "ignore-trailing-blanks", false, "ignore trailing blanks in sort keys")
+	flag.BoolVar(&s.CheckSort, "c", false, "check whether input is sorted, report the first disorder and exit 1 if not")
+	flag.BoolVar(&quietCheck, "C", false, "like -c, but do not report the first disorder")
+	flag.BoolVar(&mergeOnly, "m", false, "merge already sorted files, do not sort")
+	flag.BoolVar(&s.CheckInputs, "check-inputs", false, "with -m, fail on the first input that is not sorted")
 	flag.BoolVar(&s.HumanReadable, "h", false, "enable human-readable sort")
-	flag.BoolVar(&s.MonthCheck, "M", false, "sort month format")
+	flag.BoolVar(&s.HumanSI, "si", false, "human-readable suffixes without i are powers of 1000, not 1024")
+	flag.BoolVar(&s.MonthCheck, "M", false, "sort month format: full or short names in any case, unknown values first")
+	flag.Func("months", "month name languages for -M: en, ru or en,ru (default both)", func(list string) error {
+		var err error
+		s.MonthTables, err = sorter.ParseMonthTables(list)
+		return err
+	})
+	flag.BoolVar(&s.VersionSort, "V", false, "natural sort of version numbers: 1.9 < 1.10, 1.0~rc1 < 1.0")
+	flag.BoolVar(&s.Date, "date", false, "sort by date and time, see --date-format")
+	flag.Func("date-format", "date layout: rfc3339, datetime, iso, clf, dmy, rfc1123, unix, unixms or a Go layout like 02.01.2006; can be repeated, default tries them all", func(name string) error {
+		layout, err := sorter.ParseDateLayout(name)
+		s.DateLayouts = append(s.DateLayouts, layout)
+		return err
+	})
+	flag.Func("timezone", "time zone for dates without one: UTC, Local, Europe/Moscow or +03:00 (default UTC)", func(name string) error {
+		var err error
+		s.TimeZone, err = sorter.ParseTimeZone(name)
+		return err
+	})
+	flag.BoolVar(&s.Random, "R", false, "sort by a keyed hash of keys: random order, equal keys stay together")
+	flag.BoolVar(&s.Shuffle, "shuffle", false, "shuffle lines instead of sorting; with -u duplicates are removed first")
+	flag.Func("seed", "seed for -R and --shuffle, the same seed gives the same order", func(seed string) error {
+		s.RandomSeed = []byte(seed)
+		return nil
+	})
+	flag.Func("random-source", "read the seed for -R and --shuffle from file", func(path string) error {
+		var err error
+		s.RandomSeed, err = sorter.ReadSeed(path)
+		return err
+	})
+	flag.BoolVar(&s.Stable, "s", false, "stable sort: keep input order of lines with equal keys")
+	flag.IntVar(&s.Parallel, "parallel", min(8, runtime.NumCPU()), "number of sorts run concurrently")
 	flag.BoolVar(&s.SortType, "t", true, "swich Sort func, default SortA()")
-	flag.BoolVar(&resultToFile, "f", false, "write result of sort to result_ + filename")
+	flag.BoolVar(&s.FoldCase, "f", false, "fold lower case to upper case characters")
+	flag.BoolVar(&s.Dictionary, "d", false, "consider only blanks and alphanumeric characters")
+	flag.BoolVar(&s.IgnoreNonPrint, "i", false, "consider only printable characters")
+	flag.Func("locale", "collation: C (bytes), en or ru; default from LC_ALL, LC_COLLATE or LANG", func(name string) error {
+		var err error
+		s.Collation, err = sorter.ParseCollation(name)
+		return err
+	})
+	flag.BoolVar(&s.CollectErrors, "all-errors", false, "report every line with a bad key instead of stopping at the first one")
+	flag.Func("bad-lines", "what to do with lines whose key does not parse: strict, lenient (bad keys sort first) or quarantine", func(value string) error {
+		var err error
+		s.BadLines, err = sorter.ParseBadLinePolicy(value)
+		return err
+	})
+	flag.StringVar(&rejectPath, "reject", "", "with --bad-lines=quarantine write bad lines to this file")
+	flag.BoolFunc("z", "records end with NUL instead of newline, e.g. output of find -print0", func(string) error {
+		s.RecordSep = "\x00"
+		return nil
+	})
+	flag.Func("record-sep", "record separator instead of newline, Go escapes like \\x1e or \\r\\n are allowed", func(value string) error {
+		var err error
+		s.RecordSep, err = parseRecordSep(value)
+		return err
+	})
+	flag.Func("encoding", "input encoding: auto (by BOM), utf-8, utf-16le, utf-16be, cp1251 or koi8-r", func(name string) error {
+		var err error
+		encoding, err = sorter.ParseEncoding(name)
+		return err
+	})
+	flag.BoolVar(&keepEncoding, "keep-encoding", false, "write result in the encoding and with the BOM of the first input instead of UTF-8")
+	flag.BoolVar(&s.CSV, "csv", false, "fields are RFC 4180 CSV: quoted fields may hold separators and newlines, -F defaults to comma")
+	flag.BoolFunc("tsv", "like --csv with tab as field separator", func(string) error {
+		s.CSV, s.Separator = true, "\t"
+		return nil
+	})
+	flag.BoolVar(&s.JSON, "json", false, "records are JSON Lines; keys are JSON paths like -k .user.id:n, missing paths sort first")
+	flag.IntVar(&s.Header, "header", 0, "keep the first N records as header on top; keys may name its columns, e.g. -k price:n")
+	flag.BoolVar(&s.KeepBlank, "keep-blank", false, "keep blank lines instead of dropping them, they sort before all others")
+	flag.StringVar(&outputPath, "o", "", "write result to file instead of stdout, may be the input file itself")
+	flag.StringVar(&bufferSize, "S", "", "memory buffer size for external sort, e.g. 100M")
+	flag.StringVar(&files0From, "files0-from", "", "read input file names separated by NUL from file, - for stdin")
+	flag.StringVar(&s.TempDir, "T", os.TempDir(), "directory for temporary files of external sort")
+	preprocessFlags()
+	s.Collation, _ = sorter.ParseCollation(collationFromEnv())
 	flag.Parse()
 
-	args := flag.Args() // Получаем имя файла
-	var filename string
-	if len(args) == 0 {
-		fmt.Println("не указан файл по стандарту data.txt")
-		filename = "data.txt"
-	} else {
-		filename = args[0]
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := s.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	var err error
+	if s.BufferSize, err = sorter.ParseBufferSize(bufferSize); err != nil {
+		log.Fatal(err)
 	}
 
-	fmt.Printf(
-		"Файл: %s\ncolumn=%d, numeric=%t, reverse=%t, unique=%t, removeTBlanks=%t, checkSort=%t, resultToFile=%t\n",
-		filename,
-		s.Column,
-		s.Numeric,
-		s.Reverse,
-		s.Unique,
-		s.RemoveTBlanks,
-		s.CheckSort,
-		resultToFile,
-	)
+	names, err := inputNames(flag.Args(), files0From) // Получаем имена файлов
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if s.CheckSort || quietCheck {
+		os.Exit(checkFiles(ctx, &s, names, encoding, quietCheck))
+	}
 
-	lines, err := readLines(filename)
+	reject, err := openReject(rejectPath, s.BadLines)
 	if err != nil {
-		log.Println(err)
+		log.Fatal(err)
+	}
+	if reject != nil {
+		s.Reject = reject
 	}
 
-	if s.CheckSort {
-		if s.isSorted(lines) {
-			fmt.Println("-c : Строки отсортированны")
-			return
+	if mergeOnly {
+		if err := mergeTo(ctx, &s, names, outputPath, encoding, keepEncoding); err != nil {
+			reject.Abort()
+			log.Fatal(err)
+		}
+		if err := reject.Commit(); err != nil {
+			log.Fatal(errorf(msgWriteReject, err))
 		}
-		fmt.Println("-c : Строки не отсортированны!")
 		return
 	}
 
-	if err := s.Sort(lines); err != nil { //Если сортировка выкинула ошибку
-		log.Fatalf("ошибка сортировки: %v", err)
-	}
+	files := newInputFiles(names, encoding)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
 
-	if s.Unique {
-		lines = removeDuplicatesSorted(lines)
+	out, err := openOutput(outputPath)
+	if err != nil {
+		reject.Abort()
+		log.Fatal(err)
+	}
+	var w io.Writer = out
+	if keepEncoding {
+		enc, bom, err := files[0].sourceEncoding()
+		if err != nil {
+			out.Abort()
+			reject.Abort()
+			log.Fatal(err)
+		}
+		w = sorter.NewEncoder(out, enc, bom)
+	}
+	if err := sortTo(ctx, &s, files, w); err != nil {
+		out.Abort()
+		reject.Abort()
+		log.Fatal(err)
+	}
+	if err := out.Commit(); err != nil {
+		reject.Abort()
+		log.Fatal(errorf(msgWriteOutput, err))
 	}
-	if resultToFile { // Выбор куда выводить результат
-		writeLinesToFile(lines, "result_"+filename)
-	} else {
-		printLines(lines)
+	if err := reject.Commit(); err != nil {
+		log.Fatal(errorf(msgWriteReject, err))
 	}
-	fmt.Print("Отсортировалось")
 }
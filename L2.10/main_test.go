@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"L2.10/sorter"
+)
+
+// Сортирует файлы через sortTo и возвращает результат
+func sortFiles(s *sorter.Sorter, names ...string) (string, error) {
+	files := newInputFiles(names, sorter.EncodingAuto)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	var out strings.Builder
+	err := sortTo(context.Background(), s, files, &out)
+	return out.String(), err
+}
+
+func TestSortMultipleInputs(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("d\nb"), 0o644); err != nil { // без перевода строки в конце
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("c\n\na\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sorter.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sortFiles(s, first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Последняя строка первого файла не склеилась с первой строкой второго
+	if want := "a\nb\nc\nd\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSortInputsRecordSep(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	if err := os.WriteFile(first, []byte("b\x00x"), 0o644); err != nil { // без NUL в конце
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("1||2\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sorter.New(sorter.WithRecordSep("\x00"), sorter.WithNumeric())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sortFiles(s, first, second)
+	var se *sorter.SortError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *sorter.SortError, got %v", err)
+	}
+	if se.File != first || se.Line != 1 {
+		t.Errorf("expected %s:1, got %s:%d", first, se.File, se.Line)
+	}
+}
+
+func TestInputFilesEncoding(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	// "б\nа\n" в UTF-16LE с BOM и "в" в UTF-8 с BOM
+	if err := os.WriteFile(first, []byte{0xFF, 0xFE, 0x31, 0x04, '\n', 0, 0x30, 0x04, '\n', 0}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("\xEF\xBB\xBFв\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := newInputFiles([]string{first, second}, sorter.EncodingAuto)
+	enc, bom, err := files[0].sourceEncoding()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enc != sorter.EncodingUTF16LE || !bom {
+		t.Errorf("expected utf-16le with BOM, got %v bom=%v", enc, bom)
+	}
+
+	got, err := io.ReadAll(io.MultiReader(files[0], files[1]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "б\nа\nв\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if files[0].file != nil || files[1].file != nil {
+		t.Error("expected files to be closed after reading")
+	}
+}
+
+// Заголовок пропускается в каждом файле, а не только в первом
+func TestSortInputsHeader(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "h1.csv")
+	second := filepath.Join(dir, "h2.csv")
+	if err := os.WriteFile(first, []byte("name,price\nb,3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("name,price\na,x\nc,1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []string{"", "1"} { // в памяти и внешней сортировкой
+		s, err := sorter.New(sorter.WithCSV(","), sorter.WithHeader(1), sorter.WithKeys("name"), sorter.WithBufferSize(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := sortFiles(s, first, second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "name,price\na,x\nb,3\nc,1\n"; got != want {
+			t.Errorf("-S %q: expected %q, got %q", size, want, got)
+		}
+	}
+
+	s, err := sorter.New(sorter.WithCSV(","), sorter.WithHeader(1), sorter.WithKeys("price:n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sortFiles(s, first, second)
+	var se *sorter.SortError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *sorter.SortError, got %v", err)
+	}
+	if se.File != second || se.Line != 2 {
+		t.Errorf("expected %s:2, got %s:%d", second, se.File, se.Line)
+	}
+}
+
+func TestParseRecordSep(t *testing.T) {
+	for value, want := range map[string]string{`\x00`: "\x00", "||": "||", `\r\n`: "\r\n", `"`: `"`} {
+		if got, err := parseRecordSep(value); err != nil || got != want {
+			t.Errorf("parseRecordSep(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseRecordSep(""); err == nil {
+		t.Error("expected error for empty separator")
+	}
+}
+
+func TestInputErrorLocation(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("2\n1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("3\n\nx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sorter.New(sorter.WithNumeric())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sortFiles(s, first, second)
+	var se *sorter.SortError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *sorter.SortError, got %v", err)
+	}
+	if se.File != second || se.Line != 3 {
+		t.Errorf("expected %s:3, got %s:%d", second, se.File, se.Line)
+	}
+}
+
+// Многострочная запись CSV занимает несколько строк входа, и номера строк
+// в следующем файле не должны от этого съезжать
+func TestInputErrorLocationCSV(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "c1.csv")
+	second := filepath.Join(dir, "c2.csv")
+	if err := os.WriteFile(first, []byte("a,1\n\"x\ny\nz\",2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("b,x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, external := range []bool{false, true} {
+		opts := []sorter.Option{sorter.WithCSV(","), sorter.WithKeys("2n")}
+		if external {
+			opts = append(opts, sorter.WithBufferSize("1"))
+		}
+		s, err := sorter.New(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = sortFiles(s, first, second)
+		var se *sorter.SortError
+		if !errors.As(err, &se) {
+			t.Fatalf("external=%v: expected *sorter.SortError, got %v", external, err)
+		}
+		if se.File != second || se.Line != 1 {
+			t.Errorf("external=%v: expected %s:1, got %s:%d", external, second, se.File, se.Line)
+		}
+	}
+}
+
+func TestExpandShortFlags(t *testing.T) {
+	saved := flag.CommandLine
+	defer func() { flag.CommandLine = saved }()
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, name := range []string{"c", "g", "i", "m", "n", "r", "s", "count", "si", "ignore-trailing-blanks"} {
+		flag.Bool(name, false, "")
+	}
+	for _, name := range []string{"k", "o", "months"} {
+		flag.String(name, "", "")
+	}
+
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"-nr", []string{"-n", "-r"}},
+		{"-nrk2", []string{"-n", "-r", "-k", "2"}},
+		{"-ignore-trailing-blanks", []string{"-ignore-trailing-blanks"}},
+		{"-count", []string{"-count"}},
+		{"-months", []string{"-months"}},
+		{"-si", []string{"-si"}},
+		{"-nx", []string{"-nx"}},
+	}
+	for _, tt := range tests {
+		if got := expandShortFlags(tt.arg); !slices.Equal(got, tt.want) {
+			t.Errorf("expandShortFlags(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestInputNames(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "list")
+	if err := os.WriteFile(list, []byte("a.txt\x00dir/b c.txt\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := inputNames(nil, list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"a.txt", "dir/b c.txt"}; !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got, _ := inputNames(nil, ""); !slices.Equal(got, []string{stdinName}) {
+		t.Errorf("expected stdin without arguments, got %q", got)
+	}
+	if _, err := inputNames([]string{"x"}, list); err == nil {
+		t.Error("expected error for files together with --files0-from")
+	}
+}
+
+func TestOutputInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("b\nc\na\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := newInputFiles([]string{path}, sorter.EncodingAuto)
+	defer files[0].Close()
+	out, err := openOutput(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := sorter.New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sortTo(context.Background(), s, files, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := out.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\nc\n" {
+		t.Errorf("expected sorted file, got %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected temporary file to be renamed, got %d files", len(entries))
+	}
+}
+
+// Новый файл получает обычные права с учетом umask, как у файла, созданного с 0666
+func TestOutputNewFileMode(t *testing.T) {
+	dir := t.TempDir()
+	ref, err := os.OpenFile(filepath.Join(dir, "ref"), os.O_CREATE|os.O_WRONLY, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref.Close()
+	want, _ := os.Stat(ref.Name())
+
+	path := filepath.Join(dir, "new.txt")
+	out, err := openOutput(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.WriteString("a\n")
+	if err := out.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("expected mode %v, got %v", want.Mode().Perm(), info.Mode().Perm())
+	}
+}
+
+func TestOutputAbortKeepsTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := openOutput(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.WriteString("new\n")
+	out.Abort()
+
+	if data, _ := os.ReadFile(path); string(data) != "old\n" {
+		t.Errorf("expected target to stay untouched, got %q", data)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"L2.10/sorter"
+)
+
+// Идентификатор сообщения командной строки в каталоге
+type message int
+
+const (
+	msgSortFailed message = iota
+	msgMergeFailed
+	msgWriteOutput
+	msgWriteReject
+	msgRejectRequired
+	msgBadRecordSep
+	msgFilesWithFiles0From
+	msgStdinTwice
+	msgNoFiles0From
+)
+
+// Каталог сообщений командной строки. Ошибки sorter берут текст из своего каталога
+var catalog = map[sorter.Language]map[message]string{
+	sorter.English: {
+		msgSortFailed:          "sort failed: %w",
+		msgMergeFailed:         "merge failed: %w",
+		msgWriteOutput:         "cannot write output: %w",
+		msgWriteReject:         "cannot write rejected lines: %w",
+		msgRejectRequired:      "--bad-lines=quarantine requires --reject",
+		msgBadRecordSep:        "invalid record separator '%s'",
+		msgFilesWithFiles0From: "file operands cannot be combined with --files0-from",
+		msgStdinTwice:          "cannot read stdin while the file list is read from it",
+		msgNoFiles0From:        "no files in the --files0-from list",
+	},
+	sorter.Russian: {
+		msgSortFailed:          "ошибка сортировки: %w",
+		msgMergeFailed:         "ошибка слияния: %w",
+		msgWriteOutput:         "ошибка записи результата: %w",
+		msgWriteReject:         "ошибка записи карантина: %w",
+		msgRejectRequired:      "для --bad-lines=quarantine нужен --reject",
+		msgBadRecordSep:        "некорректный разделитель записей '%s'",
+		msgFilesWithFiles0From: "файлы нельзя указывать одновременно с --files0-from",
+		msgStdinTwice:          "нельзя читать stdin, когда из него читается список файлов",
+		msgNoFiles0From:        "в списке --files0-from нет файлов",
+	},
+}
+
+// Создает ошибку с сообщением на языке из LC_ALL, LC_MESSAGES или LANG, как у sorter.
+// %w в сообщении оборачивает ошибку
+func errorf(id message, args ...any) error {
+	msgs, ok := catalog[sorter.LanguageFromEnv()]
+	if !ok {
+		msgs = catalog[sorter.English]
+	}
+	return fmt.Errorf(msgs[id], args...)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// output — куда пишется результат: stdout или файл, который подменяется атомарно
+type output struct {
+	*bufio.Writer
+	file *os.File // временный файл рядом с целью, nil при выводе в stdout
+	path string   // целевой путь
+}
+
+// Открывает вывод. Пустой путь или "-" — stdout. Для файла результат сначала пишется
+// во временный файл в том же каталоге и переименовывается в цель только в Commit,
+// поэтому -o может указывать на входной файл, а при ошибке цель не портится
+func openOutput(path string) (*output, error) {
+	if path == "" || path == stdinName {
+		return &output{Writer: bufio.NewWriter(os.Stdout)}, nil
+	}
+
+	// Новый файл создаем как обычно, 0666 с учетом umask, у существующего сохраняем права
+	info, statErr := os.Stat(path)
+	perm := fs.FileMode(0o666)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := createTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-", perm)
+	if err != nil {
+		return nil, err
+	}
+	if statErr == nil {
+		if err := f.Chmod(perm); err != nil { // umask мог срезать права при создании
+			f.Close()
+			os.Remove(f.Name())
+			return nil, err
+		}
+	}
+
+	return &output{Writer: bufio.NewWriter(f), file: f, path: path}, nil
+}
+
+// Создает новый временный файл в dir с именем prefix и случайным хвостом, как os.CreateTemp,
+// но с правами perm, к которым применяется umask
+func createTemp(dir, prefix string, perm fs.FileMode) (*os.File, error) {
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(rand.Uint64(), 36))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"), Err: fs.ErrExist}
+}
+
+// Commit дописывает буфер, сбрасывает файл на диск и подменяет им цель.
+// У nil ничего не делает, как и Abort
+func (o *output) Commit() error {
+	if o == nil {
+		return nil
+	}
+	if err := o.Flush(); err != nil {
+		o.Abort()
+		return err
+	}
+	if o.file == nil {
+		return nil
+	}
+
+	err := o.file.Sync()
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(o.file.Name(), o.path)
+	}
+	if err != nil {
+		os.Remove(o.file.Name())
+		return err
+	}
+
+	// Чтобы переименование пережило сбой питания, синхронизируем и каталог
+	if dir, err := os.Open(filepath.Dir(o.path)); err == nil {
+		if err := dir.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+			dir.Close()
+			return err
+		}
+		dir.Close()
+	}
+	return nil
+}
+
+// Abort удаляет временный файл, цель остается нетронутой
+func (o *output) Abort() {
+	if o != nil && o.file != nil {
+		o.file.Close()
+		os.Remove(o.file.Name())
+	}
+}
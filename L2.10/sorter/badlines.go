@@ -0,0 +1,78 @@
+package sorter
+
+import (
+	"io"
+)
+
+// BadLinePolicy — что делать со строкой, ключ которой не разобрался:
+// не число при -n, -g или -h, не дата при --date, строка не JSON или в ней нет нужного поля
+type BadLinePolicy int
+
+const (
+	BadLineStrict     BadLinePolicy = iota // сортировка останавливается с ошибкой
+	BadLineLenient                         // ключ считается меньше любого значения, пропавшее поле — пустым
+	BadLineQuarantine                      // строка пишется в Reject и не сортируется
+)
+
+// ParseBadLinePolicy разбирает политику: strict, lenient или quarantine
+func ParseBadLinePolicy(value string) (BadLinePolicy, error) {
+	switch value {
+	case "strict":
+		return BadLineStrict, nil
+	case "lenient":
+		return BadLineLenient, nil
+	case "quarantine":
+		return BadLineQuarantine, nil
+	}
+	return BadLineStrict, errorf(msgBadLinePolicy, ErrInvalidOption, value)
+}
+
+func (p BadLinePolicy) String() string {
+	switch p {
+	case BadLineLenient:
+		return "lenient"
+	case BadLineQuarantine:
+		return "quarantine"
+	default:
+		return "strict"
+	}
+}
+
+// Ключ, который не разобрался. При BadLineLenient вместо ошибки получается
+// ключ меньше любого значения, иначе — ошибка без номера строки
+func (s *Sorter) badKey(k KeySpec, value string, reason, err error) (sortKey, *SortError) {
+	if s.BadLines == BadLineLenient {
+		return sortKey{invalid: true}, nil
+	}
+	return sortKey{}, s.keyError(k, value, reason, err)
+}
+
+// При BadLineQuarantine проверяет ключи строки и, если они не разбираются,
+// пишет строку в Reject. Возвращает true, если строку сортировать не нужно
+func (s *Sorter) quarantined(line string) (bool, error) {
+	if s.BadLines != BadLineQuarantine || s.checkLineKeys(line, 0) == nil {
+		return false, nil
+	}
+	return true, s.rejectLine(line)
+}
+
+// Пишет строку в Reject. Без Reject строка просто отбрасывается
+func (s *Sorter) rejectLine(line string) error {
+	if s.Reject == nil {
+		return nil
+	}
+	_, err := io.WriteString(s.Reject, line+s.recordSep())
+	return err
+}
+
+// Сравнивает ключи, из которых хотя бы один не разобрался: такой ключ меньше любого значения
+func compareInvalid(a, b bool, reverse bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a != reverse:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,103 @@
+package sorter
+
+import (
+	"context"
+	"io"
+)
+
+// DisorderError — первая строка не по порядку, найденная в режиме -c
+type DisorderError struct {
+	File string // имя входа, "-" для stdin
+	Line int    // номер строки во входе, с 1
+	Text string // сама строка
+}
+
+func (e *DisorderError) Error() string {
+	return LanguageFromEnv().sprintf(msgDisorder, e.File, e.Line, e.Text)
+}
+
+// Check проверяет, что входы, прочитанные подряд, уже отсортированы.
+// Возвращает *DisorderError для первой строки не по порядку. При Unique
+// соседние строки с равными ключами тоже считаются нарушением порядка.
+// Заголовок из Header записей в каждом входе не проверяется
+func (s *Sorter) Check(ctx context.Context, inputs []io.Reader, names []string) error {
+	return s.call().check(ctx, inputs, names)
+}
+
+// Проверяет порядок входов в рамках одного вызова
+func (s *Sorter) check(ctx context.Context, inputs []io.Reader, names []string) error {
+
+	var prev, prevFile string
+	var prevLine int
+	first := true
+	for i, r := range inputs {
+		rr := s.newRecordReader(r)
+		header, err := readHeader(rr, s.Header)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			if err := s.resolveKeyNames(header); err != nil {
+				return err
+			}
+		}
+		for n := 1; ; n++ {
+			if err := canceled(ctx, n); err != nil {
+				return err
+			}
+			line, err := rr.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			lineNo := rr.start
+			if s.skipBlank(line) { // пустые строки не сортируются, поэтому и не проверяются
+				continue
+			}
+			if s.BadLines == BadLineQuarantine && s.checkLineKeys(line, lineNo) != nil {
+				continue // строка ушла бы в карантин и в результат не попала
+			}
+
+			if !first {
+				ok, err := s.inOrder(prev, line)
+				if err != nil {
+					return s.lineKeysError(err, line, names[i], lineNo, prev, prevFile, prevLine)
+				}
+				if !ok {
+					return &DisorderError{File: names[i], Line: lineNo, Text: line}
+				}
+			}
+			prev, prevFile, prevLine, first = line, names[i], lineNo, false
+		}
+	}
+	return nil
+}
+
+// Находит, у какой из двух сравниваемых строк не разобрался ключ, и возвращает
+// ошибку с ее файлом и номером. Предыдущая строка может быть виновата только
+// в первом сравнении, поэтому сначала проверяется текущая
+func (s *Sorter) lineKeysError(err error, line, file string, lineNo int, prev, prevFile string, prevLine int) error {
+	if e := s.checkLineKeys(line, lineNo); e != nil {
+		e.File = file
+		return e
+	}
+	if e := s.checkLineKeys(prev, prevLine); e != nil {
+		e.File = prevFile
+		return e
+	}
+	return err
+}
+
+// Может ли line идти сразу после prev
+func (s *Sorter) inOrder(prev, line string) (bool, error) {
+	c := s.compareLinesB(prev, line)
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.Unique {
+		return c < 0, nil
+	}
+	return c <= 0, nil
+}
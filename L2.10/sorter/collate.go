@@ -0,0 +1,140 @@
+package sorter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Collation задает правила сравнения строковых ключей
+type Collation int
+
+const (
+	CollateBytes   Collation = iota // побайтовое сравнение, как в локали C
+	CollateEnglish                  // латиница, затем кириллица
+	CollateRussian                  // кириллица, затем латиница, ё сразу после е
+)
+
+// Алфавиты в порядке сортировки
+const (
+	latinAlphabet    = "abcdefghijklmnopqrstuvwxyz"
+	cyrillicAlphabet = "абвгдеёжзийклмнопрстуфхцчшщъыьэюя"
+)
+
+// Группы символов в порядке сортировки: пробелы < пунктуация < символы < цифры < буквы
+const (
+	groupSpace = iota + 1
+	groupPunct
+	groupSymbol
+	groupDigit
+	groupFirstScript
+	groupSecondScript
+	groupOtherLetter
+)
+
+// Номера букв в алфавитах, с 1
+var (
+	latinOrder    = alphabetOrder(latinAlphabet)
+	cyrillicOrder = alphabetOrder(cyrillicAlphabet)
+)
+
+func alphabetOrder(alphabet string) map[rune]int {
+	order := make(map[rune]int)
+	i := 1
+	for _, r := range alphabet {
+		order[r] = i
+		i++
+	}
+	return order
+}
+
+// ParseCollation разбирает имя локали: C, POSIX, en, ru, en_US.UTF-8, ru_RU.UTF-8
+func ParseCollation(name string) (Collation, error) {
+	lang, _, _ := strings.Cut(name, ".")
+	lang, _, _ = strings.Cut(lang, "_")
+	switch strings.ToLower(lang) {
+	case "", "c", "posix":
+		return CollateBytes, nil
+	case "en":
+		return CollateEnglish, nil
+	case "ru":
+		return CollateRussian, nil
+	default:
+		return CollateBytes, errorf(msgUnknownLocale, ErrInvalidOption, name)
+	}
+}
+
+// Строит из текста ключ, который сравнивается побайтово, но дает порядок локали.
+// Сначала идут веса букв без учета регистра, потом, если не fold, регистр:
+// строчная раньше заглавной, как в словарях
+func (c Collation) collationKey(text string, fold bool) string {
+	if c == CollateBytes {
+		if fold {
+			return strings.ToUpper(text)
+		}
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) * 5)
+	for _, r := range text {
+		w := c.primaryWeight(r)
+		b.Write([]byte{byte(w >> 24), byte(w >> 16), byte(w >> 8), byte(w)})
+	}
+	if fold {
+		return b.String()
+	}
+
+	b.WriteByte(0) // меньше любой группы, поэтому более короткая строка идет раньше
+	for _, r := range text {
+		if unicode.IsUpper(r) {
+			b.WriteByte(2)
+		} else {
+			b.WriteByte(1)
+		}
+	}
+	return b.String()
+}
+
+// Основной вес символа: группа в старшем байте и место внутри группы
+func (c Collation) primaryWeight(r rune) uint32 {
+	lower := unicode.ToLower(r)
+
+	first, second := latinOrder, cyrillicOrder
+	if c == CollateRussian {
+		first, second = cyrillicOrder, latinOrder
+	}
+
+	switch {
+	case first[lower] > 0:
+		return groupFirstScript<<24 | uint32(first[lower])
+	case second[lower] > 0:
+		return groupSecondScript<<24 | uint32(second[lower])
+	case unicode.IsLetter(r):
+		return groupOtherLetter<<24 | uint32(lower)
+	case unicode.IsDigit(r):
+		return groupDigit<<24 | uint32(r)
+	case unicode.IsSpace(r):
+		return groupSpace<<24 | uint32(r)
+	case unicode.IsPunct(r):
+		return groupPunct<<24 | uint32(r)
+	default:
+		return groupSymbol<<24 | uint32(r)
+	}
+}
+
+// Оставляет в тексте только нужные символы: при dictionary — буквы, цифры
+// и пробелы (-d), при ignoreNonPrint — только печатные символы (-i)
+func filterKeyText(text string, dictionary, ignoreNonPrint bool) string {
+	if !dictionary && !ignoreNonPrint {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if dictionary && !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '\t') {
+			return -1
+		}
+		if ignoreNonPrint && !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
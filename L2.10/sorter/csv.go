@@ -0,0 +1,120 @@
+package sorter
+
+import (
+	"strings"
+)
+
+// Границы поля CSV по RFC 4180: разделитель внутри кавычек поле не делит.
+// У поля в кавычках границы — содержимое без внешних кавычек, удвоенные кавычки остаются
+func csvFieldBounds(l, sep string, n int) (from, to int, ok bool) {
+	if n < 1 {
+		return 0, 0, false
+	}
+
+	pos := 0
+	for i := 1; ; i++ {
+		from, to = pos, len(l)
+		end := pos // откуда искать разделитель после поля
+		quoted := strings.HasPrefix(l[pos:], `"`)
+		if quoted {
+			from = pos + 1
+			to = closingQuote(l, from)
+			end = min(to+1, len(l))
+		}
+
+		idx := strings.Index(l[end:], sep)
+		if i == n {
+			if !quoted && idx >= 0 {
+				to = end + idx
+			}
+			return from, to, true
+		}
+		if idx < 0 {
+			return 0, 0, false
+		}
+		pos = end + idx + len(sep)
+	}
+}
+
+// Позиция закрывающей кавычки поля, содержимое которого начинается с from.
+// Удвоенная кавычка — экранированная. Без закрывающей кавычки — конец строки
+func closingQuote(l string, from int) int {
+	for j := from; j < len(l); j++ {
+		if l[j] != '"' {
+			continue
+		}
+		if j+1 < len(l) && l[j+1] == '"' {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(l)
+}
+
+// Убирает кавычки CSV из текста ключа: удвоенная кавычка становится одной,
+// а одиночные, обрамляющие поля внутри ключа из нескольких полей, выкидываются
+func csvUnquote(text string) string {
+	if !strings.Contains(text, `"`) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		if text[i] != '"' {
+			b.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '"' {
+			b.WriteByte('"')
+			i++
+		}
+	}
+	return b.String()
+}
+
+// Не закончилась ли запись CSV с разделителем полей sep: перевод строки внутри кавычек
+// ее не завершает. Как и в csvFieldBounds, кавычки открывает только кавычка в начале поля
+func csvOpenQuote(rec, sep string) bool {
+	pos := 0
+	for {
+		end := pos // откуда искать разделитель после поля
+		if strings.HasPrefix(rec[pos:], `"`) {
+			to := closingQuote(rec, pos+1)
+			if to == len(rec) {
+				return true
+			}
+			end = to + 1
+		}
+		idx := strings.Index(rec[end:], sep)
+		if idx < 0 {
+			return false
+		}
+		pos = end + idx + len(sep)
+	}
+}
+
+// Собирает запись CSV заново, беря в кавычки только поля, где они нужны:
+// с разделителем, кавычкой или переводом строки
+func (fs fieldSplitter) requote(l string) string {
+	fields := fs.fields(l)
+	for i, f := range fields {
+		if strings.Contains(f, fs.sep) || strings.ContainsAny(f, "\"\r\n") {
+			fields[i] = `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(fields, fs.sep)
+}
+
+// Пишет записи CSV с минимальными кавычками
+type csvSink struct {
+	out lineSink
+	fs  fieldSplitter
+}
+
+func (c csvSink) WriteLine(line string) error {
+	return c.out.WriteLine(c.fs.requote(line))
+}
+
+func (c csvSink) Close() error { return c.out.Close() }
@@ -0,0 +1,120 @@
+package sorter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Особые раскладки дат: число секунд или миллисекунд с начала эпохи Unix
+const (
+	LayoutUnix      = "unix"
+	LayoutUnixMilli = "unixms"
+)
+
+// Именованные раскладки дат для DateLayouts
+var datePresets = map[string]string{
+	"rfc3339":  time.RFC3339Nano, // 2024-01-17T10:03:01+03:00, дробные секунды необязательны
+	"datetime": time.DateTime,    // 2024-01-17 10:03:01
+	"iso":      time.DateOnly,    // 2024-01-17
+	"clf":      "02/Jan/2006:15:04:05 -0700",
+	"dmy":      "02.01.2006",
+	"rfc1123":  time.RFC1123Z,
+	"unix":     LayoutUnix,
+	"unixms":   LayoutUnixMilli,
+}
+
+// Раскладки, которые пробуются по порядку, если DateLayouts не задан
+var defaultDateLayouts = []string{
+	time.RFC3339Nano, time.DateTime, time.DateOnly, datePresets["clf"], datePresets["dmy"],
+	time.RFC1123Z, time.RFC1123, LayoutUnix,
+}
+
+// ParseDateLayout возвращает раскладку даты по имени: rfc3339, datetime, iso, clf, dmy,
+// rfc1123, unix, unixms. Остальные значения считаются раскладками Go, например 02.01.2006 15:04
+func ParseDateLayout(name string) (string, error) {
+	if layout, ok := datePresets[strings.ToLower(name)]; ok {
+		return layout, nil
+	}
+	if name == "" {
+		return "", errorf(msgEmptyDateLayout, ErrInvalidOption)
+	}
+	return name, nil
+}
+
+// ParseTimeZone разбирает пояс для дат без пояса: UTC, Local, имя из базы поясов
+// вроде Europe/Moscow или смещение +03:00, +0300
+func ParseTimeZone(name string) (*time.Location, error) {
+	for _, layout := range []string{"-07:00", "-0700"} {
+		if t, err := time.Parse(layout, name); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(name, offset), nil
+		}
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errorf(msgUnknownTimeZone, ErrInvalidOption, name)
+	}
+	return loc, nil
+}
+
+// Разбирает дату по первой подходящей раскладке
+func (s *Sorter) parseDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	layouts := s.DateLayouts
+	if len(layouts) == 0 {
+		layouts = defaultDateLayouts
+	}
+	loc := s.TimeZone
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var firstErr error
+	for _, layout := range layouts {
+		var t time.Time
+		var err error
+		switch layout {
+		case LayoutUnix:
+			t, err = parseUnix(text, 9)
+		case LayoutUnixMilli:
+			t, err = parseUnix(text, 6)
+		default:
+			t, err = time.ParseInLocation(layout, text, loc)
+		}
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
+// Разбирает время Unix с дробной частью: scale — сколько знаков до наносекунд
+// у целой части, 9 для секунд и 6 для миллисекунд
+func parseUnix(text string, scale int) (time.Time, error) {
+	whole, frac, _ := strings.Cut(text, ".")
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(frac) > scale {
+		frac = frac[:scale]
+	}
+	var nanos int64
+	if frac != "" {
+		if nanos, err = strconv.ParseInt(frac+strings.Repeat("0", scale-len(frac)), 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if strings.HasPrefix(whole, "-") {
+		nanos = -nanos
+	}
+
+	if scale == 9 {
+		return time.Unix(n, nanos), nil
+	}
+	return time.UnixMilli(n).Add(time.Duration(nanos)), nil
+}
@@ -0,0 +1,300 @@
+package sorter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding — кодировка входа или вывода. Внутри сортировка всегда работает с UTF-8
+type Encoding int
+
+const (
+	EncodingAuto    Encoding = iota // по BOM, без BOM — UTF-8
+	EncodingUTF8                    // UTF-8, BOM пропускается
+	EncodingUTF16LE                 // UTF-16 little endian
+	EncodingUTF16BE                 // UTF-16 big endian
+	EncodingCP1251                  // Windows-1251
+	EncodingKOI8R                   // KOI8-R
+)
+
+// Метки порядка байтов
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// ParseEncoding разбирает имя кодировки: auto, utf-8, utf-16 (по BOM, без него LE),
+// utf-16le, utf-16be, cp1251 (windows-1251), koi8-r. Регистр не важен
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return EncodingAuto, nil
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "utf-16", "utf16", "utf-16le", "utf16le":
+		return EncodingUTF16LE, nil
+	case "utf-16be", "utf16be":
+		return EncodingUTF16BE, nil
+	case "cp1251", "windows-1251":
+		return EncodingCP1251, nil
+	case "koi8-r", "koi8r":
+		return EncodingKOI8R, nil
+	}
+	return EncodingAuto, errorf(msgUnknownEncoding, ErrInvalidOption, name)
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "utf-8"
+	case EncodingUTF16LE:
+		return "utf-16le"
+	case EncodingUTF16BE:
+		return "utf-16be"
+	case EncodingCP1251:
+		return "cp1251"
+	case EncodingKOI8R:
+		return "koi8-r"
+	default:
+		return "auto"
+	}
+}
+
+// Таблица однобайтовой кодировки, nil для остальных
+func (e Encoding) table() *[128]rune {
+	switch e {
+	case EncodingCP1251:
+		return &cp1251Table
+	case EncodingKOI8R:
+		return &koi8rTable
+	}
+	return nil
+}
+
+// Порядок байтов UTF-16, nil для остальных кодировок
+func (e Encoding) byteOrder() binary.ByteOrder {
+	switch e {
+	case EncodingUTF16LE:
+		return binary.LittleEndian
+	case EncodingUTF16BE:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// BOM кодировки, nil для однобайтовых
+func (e Encoding) bom() []byte {
+	switch e {
+	case EncodingUTF8:
+		return bomUTF8
+	case EncodingUTF16LE:
+		return bomUTF16LE
+	case EncodingUTF16BE:
+		return bomUTF16BE
+	}
+	return nil
+}
+
+// Decoder читает вход в исходной кодировке и отдает UTF-8
+type Decoder struct {
+	r   *bufio.Reader
+	enc Encoding
+	bom bool   // вход начинался с BOM
+	raw []byte // прочитанные, но еще не декодированные байты: половина символа UTF-16
+	buf []byte // декодированные байты, не отданные в Read
+}
+
+// NewDecoder оборачивает r так, что из него читается UTF-8. BOM в результат не попадает.
+// При EncodingAuto кодировка определяется по BOM: UTF-8, UTF-16LE или UTF-16BE, без BOM — UTF-8.
+// Для явной UTF-8 и UTF-16 BOM тоже учитывается, а для UTF-16 без уточнения порядка — и выбирает его
+func NewDecoder(r io.Reader, enc Encoding) (*Decoder, error) {
+	d := &Decoder{r: bufio.NewReader(r), enc: enc}
+
+	head, err := d.r.Peek(3)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(head, bomUTF8) && (enc == EncodingAuto || enc == EncodingUTF8):
+		d.enc, d.bom = EncodingUTF8, true
+	case bytes.HasPrefix(head, bomUTF16LE) && (enc == EncodingAuto || enc == EncodingUTF16LE || enc == EncodingUTF16BE):
+		d.enc, d.bom = EncodingUTF16LE, true
+	case bytes.HasPrefix(head, bomUTF16BE) && (enc == EncodingAuto || enc == EncodingUTF16LE || enc == EncodingUTF16BE):
+		d.enc, d.bom = EncodingUTF16BE, true
+	case enc == EncodingAuto:
+		d.enc = EncodingUTF8
+	}
+	if d.bom {
+		d.r.Discard(len(d.enc.bom()))
+	}
+	return d, nil
+}
+
+// Encoding возвращает кодировку входа: явную или определенную по BOM
+func (d *Decoder) Encoding() Encoding { return d.enc }
+
+// BOM сообщает, начинался ли вход с BOM
+func (d *Decoder) BOM() bool { return d.bom }
+
+func (d *Decoder) Read(p []byte) (int, error) {
+	if d.enc == EncodingUTF8 {
+		return d.r.Read(p)
+	}
+
+	for len(d.buf) == 0 {
+		if err := d.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// Читает следующий кусок входа и декодирует его в buf
+func (d *Decoder) fill() error {
+	var chunk [4096]byte
+	n, err := d.r.Read(chunk[:])
+	if err != nil && err != io.EOF {
+		return err
+	}
+	eof := err == io.EOF
+
+	d.buf = d.buf[:0]
+	if t := d.enc.table(); t != nil {
+		for _, b := range chunk[:n] {
+			if b < utf8.RuneSelf {
+				d.buf = append(d.buf, b)
+			} else {
+				d.buf = utf8.AppendRune(d.buf, t[b-0x80])
+			}
+		}
+	} else {
+		d.raw = append(d.raw, chunk[:n]...)
+		d.decodeUTF16(eof)
+	}
+
+	if eof && len(d.buf) == 0 {
+		return io.EOF
+	}
+	return nil
+}
+
+// Декодирует накопленные байты UTF-16. Незаконченный символ ждет следующего куска,
+// а в конце входа, как и непарный суррогат, становится U+FFFD
+func (d *Decoder) decodeUTF16(eof bool) {
+	order := d.enc.byteOrder()
+	i := 0
+	for ; i+1 < len(d.raw); i += 2 {
+		r := rune(order.Uint16(d.raw[i:]))
+		if utf16.IsSurrogate(r) {
+			if i+3 >= len(d.raw) && !eof {
+				break // вторая половина пары еще не прочитана
+			}
+			r2 := utf8.RuneError
+			if i+3 < len(d.raw) {
+				r2 = rune(order.Uint16(d.raw[i+2:]))
+			}
+			if dr := utf16.DecodeRune(r, r2); dr != utf8.RuneError {
+				r = dr
+				i += 2
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		d.buf = utf8.AppendRune(d.buf, r)
+	}
+	d.raw = append(d.raw[:0], d.raw[i:]...)
+
+	if eof && len(d.raw) > 0 { // нечетный байт в конце
+		d.buf = utf8.AppendRune(d.buf, utf8.RuneError)
+		d.raw = d.raw[:0]
+	}
+}
+
+// Encoder перекодирует записанный в него UTF-8 в другую кодировку.
+// Символы, которых нет в однобайтовой кодировке, заменяются на '?'
+type Encoder struct {
+	w       io.Writer
+	enc     Encoding
+	bom     bool   // записать BOM перед первыми данными
+	rest    []byte // начало символа UTF-8, разрезанного между Write
+	buf     []byte
+	reverse map[rune]byte // обратная таблица однобайтовой кодировки
+}
+
+// NewEncoder оборачивает w, перекодируя UTF-8 в enc. При bom первым пишется BOM кодировки
+func NewEncoder(w io.Writer, enc Encoding, bom bool) *Encoder {
+	e := &Encoder{w: w, enc: enc, bom: bom && enc.bom() != nil}
+	if t := enc.table(); t != nil {
+		e.reverse = make(map[rune]byte, len(t))
+		for i, r := range t {
+			if r != utf8.RuneError {
+				e.reverse[r] = byte(0x80 + i)
+			}
+		}
+	}
+	return e
+}
+
+func (e *Encoder) Write(p []byte) (int, error) {
+	e.buf = e.buf[:0]
+	if e.bom {
+		e.buf = append(e.buf, e.enc.bom()...)
+		e.bom = false
+	}
+
+	if e.reverse == nil && e.enc.byteOrder() == nil { // UTF-8 пишется как есть
+		e.buf = append(e.buf, p...)
+		if _, err := e.w.Write(e.buf); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	data := p
+	if len(e.rest) > 0 {
+		data = append(e.rest, p...)
+	}
+	for len(data) > 0 && utf8.FullRune(data) {
+		r, size := utf8.DecodeRune(data)
+		e.buf = e.appendRune(e.buf, r)
+		data = data[size:]
+	}
+	e.rest = append([]byte(nil), data...)
+
+	if _, err := e.w.Write(e.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Дописывает символ в кодировке Encoder
+func (e *Encoder) appendRune(buf []byte, r rune) []byte {
+	if order := e.enc.byteOrder(); order != nil {
+		put := func(u rune) {
+			buf = append(buf, 0, 0)
+			order.PutUint16(buf[len(buf)-2:], uint16(u))
+		}
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError { // вне BMP — суррогатная пара
+			put(r1)
+			put(r2)
+		} else {
+			put(r)
+		}
+		return buf
+	}
+	if r < utf8.RuneSelf {
+		return append(buf, byte(r))
+	}
+	if b, ok := e.reverse[r]; ok {
+		return append(buf, b)
+	}
+	return append(buf, '?')
+}
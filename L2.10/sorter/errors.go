@@ -0,0 +1,94 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ошибки пакета, их текст на языке окружения. Конкретные ошибки оборачивают их, поэтому причину можно
+// проверить через errors.Is, строку с плохим ключом — через errors.As с *SortError,
+// а первую строку не по порядку — через errors.As с *DisorderError
+var (
+	ErrInvalidKey    error = reasonError(msgInvalidKey)    // ключ -k не разобрался
+	ErrInvalidOption error = reasonError(msgInvalidOption) // неверное значение опции
+	ErrMissingField  error = reasonError(msgFewerFields)   // в строке нет поля ключа
+	ErrNotNumber     error = reasonError(msgConversion)    // ключ не число или размер
+	ErrNotJSON       error = reasonError(msgLineNotJSON)   // ключ задан путем JSON, а строка не JSON
+	ErrNotDate       error = reasonError(msgLineNotDate)   // ключ --date не подошел ни к одной раскладке
+)
+
+// SortError — ключ строки не разобрался: значение не число, не дата, в строке нет поля или строка не JSON.
+// Причина проверяется через errors.Is с ErrNotNumber, ErrNotDate, ErrMissingField или ErrNotJSON
+type SortError struct {
+	File   string   // имя входа, пусто для единственного безымянного входа
+	Line   int      // номер строки во входе, с 1
+	Column int      // поле ключа, с 1
+	Path   string   // путь JSON, если ключ задан путем, а не полем
+	Value  string   // значение ключа, а если поля нет или строка не JSON — вся строка
+	Reason error    // ErrNotNumber, ErrNotDate, ErrMissingField или ErrNotJSON
+	Err    error    // ошибка разбора значения, может быть nil
+	Lang   Language // язык сообщения, пустой — из окружения
+}
+
+func (e *SortError) Error() string {
+	lang := e.Lang
+	if lang == "" {
+		lang = LanguageFromEnv()
+	}
+
+	where := lang.sprintf(msgField, e.Column)
+	if e.Path != "" {
+		where = lang.sprintf(msgPath, e.Path)
+	}
+	msg := where + ": " + lang.sprintf(reasonMessage(e.Reason), e.Value)
+	switch {
+	case e.File != "":
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, msg)
+	case e.Line > 0:
+		return lang.sprintf(msgLine, e.Line) + ": " + msg
+	default:
+		return msg
+	}
+}
+
+func (e *SortError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Reason}
+	}
+	return []error{e.Reason, e.Err}
+}
+
+// SortErrors — все строки с неразобранными ключами, если включен CollectErrors
+type SortErrors []*SortError
+
+func (e SortErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e SortErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Вызывает f для каждой ошибки ключа в err: одиночной или из SortErrors
+func eachSortError(err error, f func(*SortError)) {
+	var errs SortErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			f(e)
+		}
+		return
+	}
+	var e *SortError
+	if errors.As(err, &e) {
+		f(e)
+	}
+}
@@ -0,0 +1,220 @@
+package sorter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+)
+
+// Сколько байт сверх длины строки считаем на её хранение (заголовок строки, sortableLine)
+const lineOverhead = 64
+
+// Сколько временных файлов сливаем за один проход, чтобы не упереться в лимит дескрипторов
+const maxMergeFanIn = 16
+
+// SortExternal сортирует строки из r и пишет результат в w, не держа весь вход в памяти.
+// Вход режется на куски не больше BufferSize байт, каждый кусок сортируется через SortLines
+// и сбрасывается во временный файл в TempDir, затем куски сливаются k-путевым слиянием
+func (s *Sorter) SortExternal(ctx context.Context, r io.Reader, w io.Writer) error {
+	c := s.call()
+	in, err := c.newInputRecords([]io.Reader{r}, []string{""})
+	if err != nil {
+		return err
+	}
+	return c.sortExternal(ctx, in, w)
+}
+
+// Внешняя сортировка входов с уже прочитанным заголовком первого
+func (s *Sorter) sortExternal(ctx context.Context, in *inputRecords, w io.Writer) error {
+	header := in.header
+	if err := s.resolveKeyNames(header); err != nil {
+		return err
+	}
+	chunks, err := s.splitToChunks(ctx, in)
+	defer func() { removeFiles(chunks) }() // chunks меняется после каждого прохода слияния
+	if err != nil {
+		return err
+	}
+
+	// Сливаем пачками, пока файлов больше, чем можно открыть за раз
+	for len(chunks) > maxMergeFanIn {
+		var merged []string
+		for i := 0; i < len(chunks); i += maxMergeFanIn {
+			group := chunks[i:min(i+maxMergeFanIn, len(chunks))]
+			name, err := s.mergeToTempFile(ctx, group)
+			if err != nil {
+				removeFiles(merged)
+				return err
+			}
+			merged = append(merged, name)
+		}
+		removeFiles(chunks)
+		chunks = merged
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := s.writeHeader(bw, in.eol(), header); err != nil {
+		return err
+	}
+	sink := s.newLineSink(bw, in.eol())
+	if err := s.mergeFiles(ctx, chunks, sink); err != nil {
+		return err
+	}
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Читает вход кусками по BufferSize байт, сортирует их и пишет во временные файлы
+func (s *Sorter) splitToChunks(ctx context.Context, in *inputRecords) ([]string, error) {
+	var chunks []string
+	var lines []string
+	var nums lineNumbers // номера строк текущего куска во входе
+	var bad SortErrors   // ошибки ключей всех кусков при CollectErrors
+	size := 0
+
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		defer func() {
+			lines = lines[:0]
+			size = 0
+		}()
+
+		if err := s.sortLines(lines); err != nil {
+			err = nums.renumber(err)
+			if !s.CollectErrors {
+				return err
+			}
+			eachSortError(err, func(e *SortError) { bad = append(bad, e) })
+			return nil
+		}
+		if len(bad) > 0 { // результат уже не понадобится, ищем только остальные ошибки
+			return nil
+		}
+		name, err := writeTempChunk(s.TempDir, lines, s.recordSep())
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, name)
+		return nil
+	}
+
+	for n := 1; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return chunks, err
+		}
+		line, err := in.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return chunks, err
+		}
+		skip := s.skipBlank(line) // пропускаем пустые строки и карантин, как и readLines
+		if !skip {
+			if skip, err = s.quarantined(line); err != nil {
+				return chunks, err
+			}
+		}
+		if skip {
+			continue
+		}
+
+		lines = append(lines, line)
+		file, lineNo := in.where()
+		nums.add(len(lines), file, lineNo)
+		size += len(line) + lineOverhead
+		if size >= s.BufferSize {
+			if err := flush(); err != nil {
+				return chunks, err
+			}
+			nums = lineNumbers{}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return chunks, err
+	}
+	if len(bad) > 0 {
+		return chunks, bad
+	}
+	return chunks, nil
+}
+
+// Сливает группу файлов в новый временный файл
+func (s *Sorter) mergeToTempFile(ctx context.Context, files []string) (string, error) {
+	f, err := os.CreateTemp(s.TempDir, "l2sort-*")
+	if err != nil {
+		return "", err
+	}
+
+	bw := bufio.NewWriter(f)
+	err = s.mergeFiles(ctx, files, plainSink{bw, s.recordSep()})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Открывает отсортированные файлы и сливает их в w
+func (s *Sorter) mergeFiles(ctx context.Context, files []string, out lineSink) error {
+	sources := make([]*mergeSource, 0, len(files))
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sources = append(sources, s.newMergeSource(name, f))
+	}
+
+	h, err := s.startMerge(sources, false)
+	if err != nil {
+		return err
+	}
+	return s.mergeSources(ctx, h, out, false)
+}
+
+// Пишет отсортированный кусок во временный файл, разделяя строки sep, и возвращает его имя
+func writeTempChunk(dir string, lines []string, sep string) (string, error) {
+	f, err := os.CreateTemp(dir, "l2sort-*")
+	if err != nil {
+		return "", errorf(msgTempFile, err)
+	}
+
+	bw := bufio.NewWriter(f)
+	for _, v := range lines {
+		if _, err = bw.WriteString(v + sep); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Удаляет временные файлы
+func removeFiles(files []string) {
+	for _, name := range files {
+		os.Remove(name)
+	}
+}
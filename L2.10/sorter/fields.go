@@ -0,0 +1,165 @@
+package sorter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Правило деления строки на поля
+type fieldSplitter struct {
+	sep    string // разделитель полей, может быть многобайтовым
+	blanks bool   // поля разделены сериями пробелов и табуляций
+	csv    bool   // поля в формате CSV: в кавычках могут быть разделители и переводы строк
+}
+
+// Собирает правило деления на поля из настроек Sorter
+func (s *Sorter) splitter() fieldSplitter {
+	if s.CSV {
+		if s.Separator == "" {
+			return fieldSplitter{sep: ",", csv: true}
+		}
+		return fieldSplitter{sep: s.Separator, csv: true}
+	}
+	if s.BlankFields {
+		return fieldSplitter{blanks: true}
+	}
+	if s.Separator == "" {
+		return fieldSplitter{sep: "\t"}
+	}
+	return fieldSplitter{sep: s.Separator}
+}
+
+// Достает текст ключа k: от символа StartChar поля StartField до символа EndChar
+// поля EndField включительно, вместе с разделителями между полями. Символы считаются
+// в рунах. EndField == 0 или больше числа полей — до конца строки. Как и в GNU sort,
+// номер символа начала может увести за конец поля, но не за конец строки,
+// а если конец оказался раньше начала, ключ пустой. В CSV из ключа убираются кавычки
+func (fs fieldSplitter) keyText(l string, k *KeySpec) (string, error) {
+	from, fieldTo, ok := fs.fieldBounds(l, k.StartField)
+	if !ok {
+		return "", errorf(msgFewerFieldsThan, ErrMissingField, k.StartField, l)
+	}
+	fieldFrom := from
+
+	if k.SkipBlanks {
+		from = skipBlanks(l, from, len(l))
+	}
+	if k.StartChar > 1 {
+		from = advanceRunes(l, from, len(l), k.StartChar-1)
+	}
+
+	to := len(l)
+	if k.EndField > 0 {
+		endFrom, endTo, ok := fieldFrom, fieldTo, true
+		if k.EndField != k.StartField {
+			endFrom, endTo, ok = fs.fieldBounds(l, k.EndField)
+		}
+		switch {
+		case !ok: // поля нет — до конца строки
+		case k.EndChar == 0:
+			to = endTo
+		default:
+			if k.SkipEndBlanks {
+				endFrom = skipBlanks(l, endFrom, endTo)
+			}
+			to = advanceRunes(l, endFrom, endTo, k.EndChar)
+		}
+	}
+
+	if to < from {
+		return "", nil
+	}
+	if fs.csv {
+		return csvUnquote(l[from:to]), nil
+	}
+	return l[from:to], nil
+}
+
+// Делит строку на все поля. Значения полей CSV — без кавычек
+func (fs fieldSplitter) fields(l string) []string {
+	var res []string
+	for n := 1; ; n++ {
+		from, to, ok := fs.fieldBounds(l, n)
+		if !ok {
+			return res
+		}
+		f := l[from:to]
+		if fs.csv && from > 0 && l[from-1] == '"' { // кавычки снимаются только у поля в кавычках
+			f = csvUnquote(f)
+		}
+		res = append(res, f)
+	}
+}
+
+// Пропускает пробелы и табуляции с позиции pos, но не дальше lim
+func skipBlanks(l string, pos, lim int) int {
+	for pos < lim && isFieldBlank(l[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// Сдвигает байтовую позицию pos на n рун, но не дальше lim
+func advanceRunes(l string, pos, lim, n int) int {
+	for ; n > 0 && pos < lim; n-- {
+		if l[pos] < utf8.RuneSelf {
+			pos++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(l[pos:lim])
+		pos += size
+	}
+	return pos
+}
+
+// Возвращает байтовые границы поля с номером n (с 1) и признак, что такое поле есть
+func (fs fieldSplitter) fieldBounds(l string, n int) (from, to int, ok bool) {
+	if n < 1 {
+		return 0, 0, false
+	}
+	if fs.blanks {
+		return blankFieldBounds(l, n)
+	}
+	if fs.csv {
+		return csvFieldBounds(l, fs.sep, n)
+	}
+
+	// Пропускаем n-1 разделителей
+	for i := 1; i < n; i++ {
+		idx := strings.Index(l[from:], fs.sep)
+		if idx < 0 {
+			return 0, 0, false
+		}
+		from += idx + len(fs.sep)
+	}
+
+	to = len(l)
+	if idx := strings.Index(l[from:], fs.sep); idx >= 0 {
+		to = from + idx
+	}
+	return from, to, true
+}
+
+// Границы поля при делении по пробелам: как в GNU sort, ведущие пробелы
+// относятся к самому полю, а поле заканчивается перед следующей серией пробелов
+func blankFieldBounds(l string, n int) (from, to int, ok bool) {
+	pos := 0
+	for i := 1; i <= n; i++ {
+		if i > 1 && pos >= len(l) {
+			return 0, 0, false
+		}
+		from = pos
+		for pos < len(l) && isFieldBlank(l[pos]) {
+			pos++
+		}
+		for pos < len(l) && !isFieldBlank(l[pos]) {
+			pos++
+		}
+	}
+	return from, pos, true
+}
+
+// Пробельные символы, разделяющие поля
+func isFieldBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,77 @@
+package sorter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Читает n записей заголовка. Заголовок не сортируется и не проверяется, поэтому
+// пустые строки и строки с плохими ключами в нем остаются как есть
+func readHeader(rr *recordReader, n int) ([]string, error) {
+	var header []string
+	for len(header) < n {
+		line, err := rr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, line)
+	}
+	return header, nil
+}
+
+// Проставляет номера полей ключам, заданным именами столбцов, по первой строке заголовка
+func (s *Sorter) resolveKeyNames(header []string) error {
+	var columns map[string]int
+	resolve := func(keys []KeySpec) error {
+		for i := range keys {
+			k := &keys[i]
+			if k.StartName == "" {
+				continue
+			}
+			if len(header) == 0 {
+				return errorf(msgKeyNameNoHeader, ErrInvalidKey, k)
+			}
+			if columns == nil {
+				columns = make(map[string]int)
+				for n, name := range s.splitter().fields(header[0]) {
+					name = strings.TrimSpace(name)
+					if _, ok := columns[name]; !ok { // при повторе имени берем первый столбец
+						columns[name] = n + 1
+					}
+				}
+			}
+
+			var ok bool
+			if k.StartField, ok = columns[k.StartName]; !ok {
+				return errorf(msgKeyNoColumn, ErrInvalidKey, k, k.StartName)
+			}
+			if k.EndField, ok = columns[k.EndName]; !ok {
+				return errorf(msgKeyNoColumn, ErrInvalidKey, k, k.EndName)
+			}
+			if k.EndField < k.StartField {
+				return errorf(msgKeyColumnOrder, ErrInvalidKey, k, k.EndName, k.StartName)
+			}
+		}
+		return nil
+	}
+
+	if err := resolve(s.Keys); err != nil {
+		return err
+	}
+	return resolve(s.UniqueBy)
+}
+
+// Пишет заголовок перед результатом, без схлопывания дубликатов
+func (s *Sorter) writeHeader(w *bufio.Writer, eol string, header []string) error {
+	sink := s.requoting(plainSink{w, eol})
+	for _, line := range header {
+		if err := sink.WriteLine(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
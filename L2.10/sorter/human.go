@@ -0,0 +1,197 @@
+package sorter
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Степени суффиксов размеров: K — 1, M — 2 и так далее
+var humanPowers = map[rune]int{
+	'K': 1,
+	'M': 2,
+	'G': 3,
+	'T': 4,
+	'P': 5,
+	'E': 6,
+	'Z': 7,
+	'Y': 8,
+}
+
+// Точность вычислений с размерами, с запасом для Y и длинных мантисс
+const humanPrec = 256
+
+// Сколько значащих цифр мантиссы float64 хранит без потерь
+const maxExactDigits = 15
+
+// Размер вида 1.5G: степень суффикса и значение в байтах.
+// Обычно хватает float64, он не переполняется даже на Y. Для мантисс длиннее
+// maxExactDigits цифр значение считается в big.Float, чтобы не терять точность
+type humanSize struct {
+	power int
+	value float64
+	exact *big.Float // nil, если хватает value
+}
+
+// Разбирает размер вида 512, 1.5K, 10KiB, 2TB, -3M.
+// Суффикс с i (KiB) всегда степень 1024, без i — 1000 при si и 1024 без него
+func parseHumanSize(text string, si, decimalComma bool) (humanSize, error) {
+	numPart := text
+	suffix := ""
+	if i := strings.IndexFunc(text, unicode.IsLetter); i >= 0 {
+		numPart, suffix = text[:i], text[i:]
+	}
+
+	if decimalComma {
+		numPart = strings.Replace(numPart, ",", ".", 1)
+	}
+	digits, ok := countDecimalDigits(numPart)
+	if !ok {
+		return humanSize{}, errorf(msgBadNumber, numPart)
+	}
+
+	power, base, err := parseHumanSuffix(suffix, si)
+	if err != nil {
+		return humanSize{}, err
+	}
+
+	if digits > maxExactDigits {
+		value, _, err := big.ParseFloat(numPart, 10, humanPrec, big.ToNearestEven)
+		if err != nil {
+			return humanSize{}, err
+		}
+		mult := new(big.Float).SetPrec(humanPrec).SetInt(new(big.Int).Exp(big.NewInt(base), big.NewInt(int64(power)), nil))
+		value.Mul(value, mult)
+		f, _ := value.Float64()
+		return humanSize{power: power, value: f, exact: value}, nil
+	}
+
+	mant, err := strconv.ParseFloat(numPart, 64)
+	if err != nil {
+		return humanSize{}, err
+	}
+	return humanSize{power: power, value: mant * math.Pow(float64(base), float64(power))}, nil
+}
+
+// Значение в big.Float для точных вычислений
+func (h humanSize) bigValue() *big.Float {
+	if h.exact != nil {
+		return h.exact
+	}
+	return new(big.Float).SetPrec(humanPrec).SetFloat64(h.value)
+}
+
+// Разбирает суффикс: K, k, Ki, KiB, KB, B. Возвращает степень и основание
+func parseHumanSuffix(suffix string, si bool) (power int, base int64, err error) {
+	base = 1024
+	if si {
+		base = 1000
+	}
+	if suffix == "" || suffix == "B" {
+		return 0, base, nil
+	}
+
+	runes := []rune(suffix)
+	power, ok := humanPowers[unicode.ToUpper(runes[0])]
+	if !ok {
+		return 0, 0, errorf(msgUnknownSuffix, suffix)
+	}
+
+	switch string(runes[1:]) {
+	case "", "B":
+	case "i", "iB":
+		base = 1024
+	default:
+		return 0, 0, errorf(msgUnknownSuffix, suffix)
+	}
+	return power, base, nil
+}
+
+// Проверяет, что строка — десятичное число: знак, цифры и необязательная дробная часть.
+// Возвращает число значащих цифр
+func countDecimalDigits(s string) (int, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, false
+	}
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	return len(digits), true
+}
+
+// Сравнение размеров как в GNU sort -h: сначала знак, потом суффикс, потом значение.
+// Так 2K всегда больше 1500, а для отрицательных больший суффикс меньше
+func compareHumanSizes(a, b humanSize, reverse bool) int {
+	res := compareHumanSizesAsc(a, b)
+	if reverse {
+		return -res
+	}
+	return res
+}
+
+func compareHumanSizesAsc(a, b humanSize) int {
+	sa, sb := sign(a.value), sign(b.value)
+	if sa != sb {
+		return compareInts(sa, sb, false)
+	}
+	if sa == 0 {
+		return 0
+	}
+	if a.power != b.power {
+		return sa * compareInts(a.power, b.power, false)
+	}
+	if a.exact != nil || b.exact != nil {
+		return a.bigValue().Cmp(b.bigValue())
+	}
+	return compareFloats(a.value, b.value, false)
+}
+
+// Знак числа: -1, 0 или 1
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Для первода строк вида 2K в число 2048 сразу в байтах, дробная часть отбрасывается
+func toHumanFormat(s string) (int, error) {
+	h, err := parseHumanSize(s, false, false)
+	if err != nil {
+		return 0, err
+	}
+
+	v := h.bigValue()
+	n, acc := v.Int64()
+	if n > math.MaxInt || n < math.MinInt ||
+		acc != big.Exact && (n == math.MaxInt64 || n == math.MinInt64) {
+		return 0, errorf(msgIntOverflow)
+	}
+	return int(n), nil
+}
+
+// ParseBufferSize разбирает размер буфера вида 512K, 100M, 1G в байты, пустая строка — 0
+func ParseBufferSize(size string) (int, error) {
+	if size == "" {
+		return 0, nil
+	}
+	n, err := toHumanFormat(size)
+	if err != nil {
+		return 0, errorf(msgBadBufferSize, ErrInvalidOption, size, err)
+	}
+	if n <= 0 {
+		return 0, errorf(msgBufferNotPositive, ErrInvalidOption, size)
+	}
+	return n, nil
+}
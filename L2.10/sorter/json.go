@@ -0,0 +1,101 @@
+package sorter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Шаг пути JSON: ключ объекта или индекс массива
+type jsonStep struct {
+	key   string
+	index int // индекс массива, -1 для ключа объекта
+}
+
+// Разбирает путь JSON вида .user.id или .items[0].name. Путь "." — весь документ
+func parseJSONPath(path string) ([]jsonStep, error) {
+	rest, ok := strings.CutPrefix(path, ".")
+	if !ok {
+		return nil, errorf(msgPathNoDot, path)
+	}
+
+	var steps []jsonStep
+	for rest != "" {
+		switch rest[0] {
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errorf(msgPathNoBracket, path)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 0 {
+				return nil, errorf(msgPathBadIndex, path, rest[1:end])
+			}
+			steps = append(steps, jsonStep{index: n})
+			rest = rest[end+1:]
+		case '.':
+			rest = rest[1:]
+			if len(steps) == 0 || rest == "" || rest[0] == '.' || rest[0] == '[' {
+				return nil, errorf(msgPathEmptyKey, path)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if len(steps) > 0 && path[len(path)-len(rest)-1] == ']' {
+				return nil, errorf(msgPathDotAfterIndex, path)
+			}
+			steps = append(steps, jsonStep{key: rest[:end], index: -1})
+			rest = rest[end:]
+		}
+	}
+	return steps, nil
+}
+
+// Достает из строки JSON значение по пути как текст ключа: строка — без кавычек,
+// число, true и false — как записаны, объект и массив — исходным текстом.
+// found false, если пути в документе нет или значение null. Ошибка — строка не JSON
+func jsonKeyText(line, path string) (text string, found bool, err error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return "", false, err
+	}
+	if !json.Valid([]byte(line)) {
+		return "", false, ErrNotJSON
+	}
+
+	raw := json.RawMessage(line)
+	for _, step := range steps {
+		if step.index < 0 {
+			var obj map[string]json.RawMessage
+			if json.Unmarshal(raw, &obj) != nil { // не объект — пути нет
+				return "", false, nil
+			}
+			if raw, found = obj[step.key]; !found {
+				return "", false, nil
+			}
+			continue
+		}
+
+		var arr []json.RawMessage
+		if json.Unmarshal(raw, &arr) != nil || step.index >= len(arr) {
+			return "", false, nil
+		}
+		raw = arr[step.index]
+	}
+
+	text = strings.TrimSpace(string(raw))
+	switch {
+	case text == "null":
+		return "", false, nil
+	case strings.HasPrefix(text, `"`):
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return "", false, err
+		}
+		return str, true, nil
+	default:
+		return text, true, nil
+	}
+}
@@ -0,0 +1,467 @@
+package sorter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeySpec описывает один ключ сортировки в формате -k POS1[,POS2][OPTS]
+type KeySpec struct {
+	StartField     int    // первое поле ключа, нумерация с 1
+	StartChar      int    // первый символ ключа в StartField, с 1, 0 — с начала поля
+	EndField       int    // последнее поле ключа, 0 — до конца строки
+	EndChar        int    // последний символ ключа в EndField, 0 — до конца поля
+	StartName      string // имя первого столбца из заголовка, по нему находится StartField
+	EndName        string // имя последнего столбца из заголовка, по нему находится EndField
+	Path           string // путь JSON вместо полей: .user.id, .items[0]
+	SkipBlanks     bool   // пропускать пробелы в начале StartField перед отсчетом символов (b у POS1)
+	SkipEndBlanks  bool   // пропускать пробелы в начале EndField перед отсчетом символов (b у POS2)
+	Numeric        bool
+	Reverse        bool
+	HumanReadable  bool
+	MonthCheck     bool
+	RemoveTBlanks  bool // убирать хвостовые пробелы ключа
+	GeneralNumeric bool // числа с плавающей точкой, как -g
+	Version        bool // версии и натуральный порядок, как -V
+	Date           bool // даты по раскладкам DateLayouts, как --date
+	Random         bool // случайный порядок по хешу ключа, как -R
+	FoldCase       bool // без учета регистра, как -f
+	Dictionary     bool // только буквы, цифры и пробелы, как -d
+	IgnoreNonPrint bool // без непечатных символов, как -i
+}
+
+// Ключ одной строки, уже приведенный к типу сравнения
+type sortKey struct {
+	num   int
+	nsec  int // наносекунды даты, секунды с начала эпохи — в num
+	float float64
+	human humanSize
+	str   string
+
+	invalid bool // ключ не разобрался при BadLineLenient или строка пустая при KeepBlank
+}
+
+// ParseKeySpec разбирает ключ вида 2, 2,2, 2,2n, 2n,3r, 2.3,2.5b.
+// Позиция — номер поля и, через точку, номер символа в нем.
+// Столбцы можно назвать по заголовку: price:n, from,to:r, а в JSON — задать путем: .user.id:n
+func ParseKeySpec(spec string) (KeySpec, error) {
+	var k KeySpec
+	if strings.HasPrefix(spec, ".") {
+		return parsePathKey(spec)
+	}
+	if spec != "" && (spec[0] < '0' || spec[0] > '9') {
+		return parseNamedKey(spec)
+	}
+
+	startPart, endPart, hasEnd := strings.Cut(spec, ",")
+
+	field, char, opts := splitKeyPos(startPart)
+	start, err := strconv.Atoi(field)
+	if err != nil || start < 1 {
+		return KeySpec{}, errorf(msgKeyBadStart, ErrInvalidKey, spec)
+	}
+	k.StartField = start
+	if char != "" {
+		if k.StartChar, err = strconv.Atoi(char); err != nil || k.StartChar < 1 {
+			return KeySpec{}, errorf(msgKeyBadStartChar, ErrInvalidKey, spec)
+		}
+	}
+	if err := k.applyOptions(opts, false); err != nil {
+		return KeySpec{}, fmt.Errorf("%w '%s': %w", ErrInvalidKey, spec, err)
+	}
+
+	if hasEnd {
+		field, char, opts := splitKeyPos(endPart)
+		end, err := strconv.Atoi(field)
+		if err != nil || end < start {
+			return KeySpec{}, errorf(msgKeyBadEnd, ErrInvalidKey, spec)
+		}
+		k.EndField = end
+		if char != "" { // .0 у конца, как в GNU sort, — до конца поля
+			if k.EndChar, err = strconv.Atoi(char); err != nil {
+				return KeySpec{}, errorf(msgKeyBadEndChar, ErrInvalidKey, spec)
+			}
+		}
+		if err := k.applyOptions(opts, true); err != nil {
+			return KeySpec{}, fmt.Errorf("%w '%s': %w", ErrInvalidKey, spec, err)
+		}
+	}
+
+	return k, nil
+}
+
+// Разбирает ключ по именам столбцов NAME[,NAME][:OPTS]. Номера полей
+// проставляются позже, когда прочитан заголовок
+func parseNamedKey(spec string) (KeySpec, error) {
+	var k KeySpec
+	names, opts := spec, ""
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		names, opts = spec[:i], spec[i+1:]
+	}
+
+	var hasEnd bool
+	k.StartName, k.EndName, hasEnd = strings.Cut(names, ",")
+	if k.StartName == "" || hasEnd && k.EndName == "" {
+		return KeySpec{}, errorf(msgKeyEmptyName, ErrInvalidKey, spec)
+	}
+	if err := k.applyOptions(opts, false); err != nil {
+		return KeySpec{}, fmt.Errorf("%w '%s': %w", ErrInvalidKey, spec, err)
+	}
+	if hasEnd {
+		k.SkipEndBlanks = k.SkipBlanks
+	} else {
+		k.EndName = k.StartName // один столбец, как -k 2,2
+	}
+	return k, nil
+}
+
+// Разбирает ключ по пути JSON PATH[:OPTS]
+func parsePathKey(spec string) (KeySpec, error) {
+	var k KeySpec
+	path, opts := spec, ""
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		path, opts = spec[:i], spec[i+1:]
+	}
+	if _, err := parseJSONPath(path); err != nil {
+		return KeySpec{}, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	k.Path = path
+	if err := k.applyOptions(opts, false); err != nil {
+		return KeySpec{}, fmt.Errorf("%w '%s': %w", ErrInvalidKey, spec, err)
+	}
+	return k, nil
+}
+
+// Делит позицию ключа F[.C][OPTS] на номер поля, номер символа и буквы модификаторов
+func splitKeyPos(pos string) (field, char, opts string) {
+	digits := func(s string) int {
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i < 0 {
+			return len(s)
+		}
+		return i
+	}
+
+	i := digits(pos)
+	field, pos = pos[:i], pos[i:]
+	if rest, ok := strings.CutPrefix(pos, "."); ok {
+		i = digits(rest)
+		char, pos = rest[:i], rest[i:]
+		if char == "" {
+			char = "-" // точка без номера символа — ошибка разбора
+		}
+	}
+	return field, char, pos
+}
+
+// Включает модификаторы ключа по буквам как у одноименных флагов.
+// b относится к той позиции, после которой стоит: к началу или к концу ключа
+func (k *KeySpec) applyOptions(opts string, end bool) error {
+	for _, r := range opts {
+		switch r {
+		case 'n':
+			k.Numeric = true
+		case 'r':
+			k.Reverse = true
+		case 'h':
+			k.HumanReadable = true
+		case 'M':
+			k.MonthCheck = true
+		case 'b':
+			if end {
+				k.SkipEndBlanks = true
+			} else {
+				k.SkipBlanks = true
+			}
+		case 'g':
+			k.GeneralNumeric = true
+		case 'V':
+			k.Version = true
+		case 'D':
+			k.Date = true
+		case 'R':
+			k.Random = true
+		case 'f':
+			k.FoldCase = true
+		case 'd':
+			k.Dictionary = true
+		case 'i':
+			k.IgnoreNonPrint = true
+		default:
+			return errorf(msgUnknownModifier, r)
+		}
+	}
+	if k.typeCount() > 1 {
+		return errorf(msgModifierConflict)
+	}
+	return nil
+}
+
+// Сколько типов сравнения включено в ключе
+func (k KeySpec) typeCount() int {
+	n := 0
+	for _, on := range []bool{k.Numeric, k.GeneralNumeric, k.HumanReadable, k.MonthCheck, k.Version, k.Date, k.Random} {
+		if on {
+			n++
+		}
+	}
+	return n
+}
+
+// Есть ли у ключа собственные модификаторы
+func (k KeySpec) hasOptions() bool {
+	return k.Numeric || k.Reverse || k.HumanReadable || k.MonthCheck || k.RemoveTBlanks || k.GeneralNumeric || k.Version || k.Date || k.Random ||
+		k.FoldCase || k.Dictionary || k.IgnoreNonPrint || k.SkipBlanks || k.SkipEndBlanks
+}
+
+// Сравнивается ли ключ как целое число. -R хранит хеш
+func (k KeySpec) isNumeric() bool {
+	return k.Numeric || k.MonthCheck || k.Random
+}
+
+// String возвращает ключ в том же формате, в котором он задается флагом -k
+func (k KeySpec) String() string {
+	var opts strings.Builder
+	for _, o := range []struct {
+		on bool
+		r  byte
+	}{{k.Numeric, 'n'}, {k.GeneralNumeric, 'g'}, {k.HumanReadable, 'h'}, {k.MonthCheck, 'M'}, {k.Version, 'V'}, {k.Date, 'D'}, {k.Random, 'R'}, {k.FoldCase, 'f'}, {k.Dictionary, 'd'}, {k.IgnoreNonPrint, 'i'}, {k.Reverse, 'r'}} {
+		if o.on {
+			opts.WriteByte(o.r)
+		}
+	}
+
+	var b strings.Builder
+	if k.Path != "" || k.StartName != "" {
+		switch {
+		case k.Path != "":
+			b.WriteString(k.Path)
+		case k.EndName != k.StartName:
+			b.WriteString(k.StartName + "," + k.EndName)
+		default:
+			b.WriteString(k.StartName)
+		}
+		if k.SkipBlanks || opts.Len() > 0 {
+			b.WriteByte(':')
+		}
+		if k.SkipBlanks {
+			b.WriteByte('b')
+		}
+		b.WriteString(opts.String())
+		return b.String()
+	}
+
+	writePos := func(field, char int, blanks bool) {
+		b.WriteString(strconv.Itoa(field))
+		if char > 0 {
+			b.WriteString("." + strconv.Itoa(char))
+		}
+		if blanks {
+			b.WriteByte('b')
+		}
+	}
+	writePos(k.StartField, k.StartChar, k.SkipBlanks)
+	if k.EndField > 0 {
+		b.WriteByte(',')
+		writePos(k.EndField, k.EndChar, k.SkipEndBlanks)
+	}
+	b.WriteString(opts.String())
+	return b.String()
+}
+
+// Возвращает ключи сортировки. Если -k не задан, ключ строится по Column,
+// а в JSON — весь документ. Ключи без своих модификаторов наследуют общие флаги, как в GNU sort
+func (s *Sorter) keys() []KeySpec {
+	if len(s.Keys) == 0 {
+		k := KeySpec{StartField: 1} // Column 0 — вся строка
+		switch {
+		case s.JSON:
+			k = KeySpec{Path: "."}
+		case s.Column > 0:
+			k = KeySpec{StartField: s.Column, EndField: s.Column}
+		}
+		return []KeySpec{s.withGlobalOptions(k)}
+	}
+
+	keys := make([]KeySpec, len(s.Keys))
+	for i, k := range s.Keys {
+		if !k.hasOptions() {
+			k = s.withGlobalOptions(k)
+		}
+		keys[i] = k
+	}
+	return keys
+}
+
+// Копирует в ключ общие флаги Sorter
+func (s *Sorter) withGlobalOptions(k KeySpec) KeySpec {
+	k.Numeric = s.Numeric
+	k.Reverse = s.Reverse
+	k.HumanReadable = s.HumanReadable
+	k.MonthCheck = s.MonthCheck
+	k.RemoveTBlanks = s.RemoveTBlanks
+	k.SkipBlanks = s.SkipBlanks
+	k.SkipEndBlanks = s.SkipBlanks
+	k.GeneralNumeric = s.GeneralNumeric
+	k.Version = s.VersionSort
+	k.Date = s.Date
+	k.Random = s.Random
+	k.FoldCase = s.FoldCase
+	k.Dictionary = s.Dictionary
+	k.IgnoreNonPrint = s.IgnoreNonPrint
+	return k
+}
+
+// Достает ключ из строки и приводит его к типу сравнения. Номер строки
+// в ошибке не заполнен, его проставляет вызывающий
+func (s *Sorter) buildKey(k KeySpec, line string) (sortKey, *SortError) {
+	if s.KeepBlank && isBlank(line) { // оставленные пустые строки меньше любого ключа
+		return sortKey{invalid: true}, nil
+	}
+
+	var text string
+	if k.Path != "" {
+		var found bool
+		var err error
+		text, found, err = jsonKeyText(line, k.Path)
+		switch {
+		case errors.Is(err, ErrNotJSON):
+			return s.badKey(k, line, ErrNotJSON, nil)
+		case err != nil:
+			return sortKey{}, s.keyError(k, line, ErrInvalidKey, err)
+		}
+		if !found { // пропавший путь и null меньше любого значения
+			return sortKey{invalid: true}, nil
+		}
+	} else {
+		var err error
+		if text, err = s.splitter().keyText(line, &k); err != nil {
+			if s.BadLines != BadLineLenient {
+				return sortKey{}, s.keyError(k, line, ErrMissingField, nil)
+			}
+			text = "" // как в GNU sort, пропавшее поле — пустой ключ
+		}
+	}
+
+	// Если нужно, убираем хвостовые пробелы сразу
+	if k.RemoveTBlanks {
+		text = strings.TrimRight(text, " \t")
+	}
+
+	switch {
+	case k.Numeric:
+		// Числа, как и в GNU sort, могут начинаться с пробелов, например при делении по пробелам
+		n, err := strconv.Atoi(strings.TrimLeft(text, " \t"))
+		if err != nil {
+			return s.badKey(k, text, ErrNotNumber, err)
+		}
+		return sortKey{num: n}, nil
+	case k.GeneralNumeric:
+		f, err := s.parseGeneralNumeric(text)
+		if err != nil {
+			return s.badKey(k, text, ErrNotNumber, err)
+		}
+		return sortKey{float: f}, nil
+	case k.HumanReadable:
+		h, err := parseHumanSize(strings.TrimLeft(text, " \t"), s.HumanSI, s.DecimalComma)
+		if err != nil {
+			return s.badKey(k, text, ErrNotNumber, err)
+		}
+		return sortKey{human: h}, nil
+	case k.MonthCheck:
+		return sortKey{num: s.month(text)}, nil
+	case k.Date:
+		t, err := s.parseDate(text)
+		if err != nil {
+			return s.badKey(k, text, ErrNotDate, err)
+		}
+		return sortKey{num: int(t.Unix()), nsec: t.Nanosecond()}, nil
+	case k.Version:
+		return sortKey{str: text}, nil
+	case k.Random: // хешируется ключ после -f, -d и -i, чтобы равные для них ключи остались рядом
+		text = filterKeyText(text, k.Dictionary, k.IgnoreNonPrint)
+		return sortKey{num: randomHash(s.seed, s.Collation.collationKey(text, k.FoldCase))}, nil
+	default:
+		text = filterKeyText(text, k.Dictionary, k.IgnoreNonPrint)
+		return sortKey{str: s.Collation.collationKey(text, k.FoldCase)}, nil
+	}
+}
+
+// Собирает ошибку ключа k без номера строки
+func (s *Sorter) keyError(k KeySpec, value string, reason, err error) *SortError {
+	return &SortError{Column: k.StartField, Path: k.Path, Value: value, Reason: reason, Err: err, Lang: s.Language}
+}
+
+// Достает все ключи строки в dst. В ошибке проставляется номер строки lineNo
+func (s *Sorter) buildLineKeys(specs []KeySpec, line string, lineNo int, dst []sortKey) *SortError {
+	for j, spec := range specs {
+		key, err := s.buildKey(spec, line)
+		if err != nil {
+			err.Line = lineNo
+			return err
+		}
+		dst[j] = key
+	}
+	return nil
+}
+
+// Проверяет, что все ключи строки разбираются, не сохраняя их
+func (s *Sorter) checkLineKeys(line string, lineNo int) *SortError {
+	for _, spec := range s.keys() {
+		if _, err := s.buildKey(spec, line); err != nil {
+			err.Line = lineNo
+			return err
+		}
+	}
+	return nil
+}
+
+// Сравнивает строки только по ключам specs, без сравнения последней надежды
+func (s *Sorter) compareByKeys(specs []KeySpec, a, b string) (int, error) {
+	for i := range specs {
+		ka, err := s.buildKey(specs[i], a)
+		if err != nil {
+			return 0, err
+		}
+		kb, err := s.buildKey(specs[i], b)
+		if err != nil {
+			return 0, err
+		}
+
+		if c := compareKeys(&specs[i], &ka, &kb); c != 0 {
+			return c, nil
+		}
+	}
+	return 0, nil
+}
+
+// Сравнивает два ключа по правилам KeySpec. Принимает указатели,
+// чтобы не копировать структуры в самом горячем месте сортировки
+func compareKeys(k *KeySpec, a, b *sortKey) int {
+	if a.invalid || b.invalid {
+		return compareInvalid(a.invalid, b.invalid, k.Reverse)
+	}
+
+	switch {
+	case k.GeneralNumeric:
+		return compareFloats(a.float, b.float, k.Reverse)
+	case k.HumanReadable:
+		return compareHumanSizes(a.human, b.human, k.Reverse)
+	case k.Version:
+		if k.Reverse {
+			return compareVersions(b.str, a.str)
+		}
+		return compareVersions(a.str, b.str)
+	case k.Date: // секунды, а при равенстве — наносекунды
+		if c := compareInts(a.num, b.num, k.Reverse); c != 0 {
+			return c
+		}
+		return compareInts(a.nsec, b.nsec, k.Reverse)
+	case k.isNumeric():
+		return compareInts(a.num, b.num, k.Reverse)
+	default:
+		return compareStrings(a.str, b.str, k.Reverse)
+	}
+}
@@ -0,0 +1,260 @@
+package sorter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Как часто при чтении и записи строк проверяется отмена контекста
+const ctxCheckEvery = 1024
+
+// Проверяет отмену контекста раз в ctxCheckEvery строк, а не на каждой
+func canceled(ctx context.Context, n int) error {
+	if n%ctxCheckEvery != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// Разделитель записей: RecordSep или перевод строки
+func (s *Sorter) recordSep() string {
+	if s.RecordSep == "" {
+		return "\n"
+	}
+	return s.RecordSep
+}
+
+// Читает записи любой длины, разделенные sep. Последняя запись может быть без разделителя
+type recordReader struct {
+	br    *bufio.Reader
+	sep   string
+	csv   bool   // разделитель внутри кавычек CSV запись не завершает
+	comma string // разделитель полей CSV
+	read  bool   // прочитана хотя бы одна запись
+	crlf  bool   // первая строка кончалась на \r\n, только для sep "\n"
+
+	lines int // сколько физических строк прочитано, запись CSV может занимать несколько
+	start int // номер физической строки, с которой началась последняя запись
+}
+
+// Создает читатель записей с разделителем Sorter
+func (s *Sorter) newRecordReader(r io.Reader) *recordReader {
+	return &recordReader{br: bufio.NewReader(r), sep: s.recordSep(), csv: s.CSV, comma: s.splitter().sep}
+}
+
+// Читает следующую запись без разделителя. Для строк убирает и \r перед \n
+func (rr *recordReader) next() (string, error) {
+	rr.start = rr.lines + 1
+	rec, err := rr.readRecord()
+	for rr.csv && err == nil && csvOpenQuote(rec, rr.comma) { // многострочная запись CSV
+		var more string
+		more, err = rr.readRecord()
+		rec += more
+	}
+	if err != nil && !(errors.Is(err, io.EOF) && rec != "") {
+		return "", err
+	}
+
+	rec, hasSep := strings.CutSuffix(rec, rr.sep)
+	if rr.sep == "\n" {
+		var cr bool
+		rec, cr = strings.CutSuffix(rec, "\r")
+		if !rr.read {
+			rr.crlf = cr && hasSep
+		}
+	}
+	rr.read = true
+	return rec, nil
+}
+
+// Читает физическую строку вместе с разделителем, разделитель может быть многобайтовым
+func (rr *recordReader) readRecord() (string, error) {
+	rec, err := rr.readUntilSep()
+	if rec != "" {
+		rr.lines++
+	}
+	return rec, err
+}
+
+func (rr *recordReader) readUntilSep() (string, error) {
+	last := rr.sep[len(rr.sep)-1]
+	if len(rr.sep) == 1 {
+		return rr.br.ReadString(last)
+	}
+
+	var b strings.Builder
+	for {
+		part, err := rr.br.ReadString(last)
+		b.WriteString(part)
+		if err != nil || strings.HasSuffix(b.String(), rr.sep) {
+			return b.String(), err
+		}
+	}
+}
+
+// Окончание записей для вывода: \r\n, если им кончалась первая строка входа, иначе разделитель
+func (rr *recordReader) eol() string {
+	if rr.crlf {
+		return "\r\n"
+	}
+	return rr.sep
+}
+
+// Записи нескольких входов подряд, как при сортировке списка файлов. Заголовок
+// из Header записей пропускается в каждом входе, заголовок первого сохраняется
+type inputRecords struct {
+	s      *Sorter
+	inputs []io.Reader
+	names  []string
+	i      int           // номер текущего входа
+	rr     *recordReader // текущий вход, nil — входов нет
+	first  *recordReader // первый вход, где была хотя бы одна запись: по нему выбирается окончание строк
+	header []string      // заголовок первого входа
+}
+
+// Открывает первый вход и читает его заголовок
+func (s *Sorter) newInputRecords(inputs []io.Reader, names []string) (*inputRecords, error) {
+	in := &inputRecords{s: s, inputs: inputs, names: names}
+	if len(inputs) == 0 {
+		return in, nil
+	}
+	header, err := in.open(0)
+	if err != nil {
+		return nil, err
+	}
+	in.header = header
+	return in, nil
+}
+
+// Переходит ко входу i и читает его заголовок
+func (in *inputRecords) open(i int) ([]string, error) {
+	in.i = i
+	in.rr = in.s.newRecordReader(in.inputs[i])
+	header, err := readHeader(in.rr, in.s.Header)
+	in.noteRead()
+	return header, err
+}
+
+// Запоминает первый вход, из которого что-то прочитано
+func (in *inputRecords) noteRead() {
+	if in.first == nil && in.rr.read {
+		in.first = in.rr
+	}
+}
+
+// Читает следующую запись, в конце входа переходя к следующему
+func (in *inputRecords) next() (string, error) {
+	for in.rr != nil {
+		rec, err := in.rr.next()
+		if err != io.EOF {
+			in.noteRead()
+			return rec, err
+		}
+		if in.i+1 == len(in.inputs) {
+			break
+		}
+		if _, err := in.open(in.i + 1); err != nil {
+			return "", err
+		}
+	}
+	return "", io.EOF
+}
+
+// Имя входа и номер строки, с которой началась последняя прочитанная запись
+func (in *inputRecords) where() (string, int) {
+	return in.names[in.i], in.rr.start
+}
+
+// Окончание записей для вывода, как у первого непустого входа
+func (in *inputRecords) eol() string {
+	if in.first == nil {
+		return in.s.recordSep()
+	}
+	return in.first.eol()
+}
+
+// Пропускается ли запись при чтении: пустые строки выкидываются, если не задан KeepBlank
+func (s *Sorter) skipBlank(line string) bool {
+	return !s.KeepBlank && isBlank(line)
+}
+
+// Читает все непустые строки и запоминает, с какой строки какого входа начинается каждая:
+// номера сдвигают заголовки, пропущенные пустые и ушедшие в карантин строки
+// и многострочные записи CSV
+func (s *Sorter) readLines(ctx context.Context, in *inputRecords) ([]string, lineNumbers, error) {
+	var lines []string
+	var nums lineNumbers
+	for n := 1; ; n++ {
+		if err := canceled(ctx, n); err != nil {
+			return nil, nums, err
+		}
+		line, err := in.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nums, err
+		}
+		skip := s.skipBlank(line) // пропускаем пустые строки
+		if !skip {
+			if skip, err = s.quarantined(line); err != nil {
+				return nil, nums, err
+			}
+		}
+		if skip {
+			continue
+		}
+		lines = append(lines, line)
+		file, lineNo := in.where()
+		nums.add(len(lines), file, lineNo)
+	}
+	return lines, nums, nil
+}
+
+// Соответствие номера строки в прочитанном срезе и входа с номером строки в нем.
+// Хранит только места, где номера во входе перестают идти подряд
+type lineNumbers struct {
+	jumps []lineJump // по возрастанию index
+}
+
+// Строка среза index начинается со строки line входа file, номера с 1
+type lineJump struct {
+	index int
+	file  string
+	line  int
+}
+
+// Запоминает, что строка среза с номером i начинается со строки line входа file.
+// Строки добавляются по порядку
+func (n *lineNumbers) add(i int, file string, line int) {
+	if f, l := n.of(i); f != file || l != line {
+		n.jumps = append(n.jumps, lineJump{i, file, line})
+	}
+}
+
+// Вход и номер строки в нем для строки среза с номером i, с 1
+func (n lineNumbers) of(i int) (string, int) {
+	j := sort.Search(len(n.jumps), func(j int) bool { return n.jumps[j].index > i })
+	if j == 0 {
+		return "", i
+	}
+	last := n.jumps[j-1]
+	return last.file, last.line + i - last.index
+}
+
+// Переводит номера строк в ошибках ключей из номеров в срезе во вход и номер строки в нем
+func (n lineNumbers) renumber(err error) error {
+	eachSortError(err, func(e *SortError) {
+		e.File, e.Line = n.of(e.Line)
+	})
+	return err
+}
+
+// Проверяет, состоит ли строка только из пробельных символов
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
@@ -0,0 +1,206 @@
+package sorter
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"io"
+)
+
+// Merge сливает уже отсортированные входы в w без пересортировки.
+// Ключ каждой строки проверяется при чтении, ошибка — *SortError с файлом и строкой.
+// В памяти держится по одной строке на вход, поэтому размер входов не важен.
+// При CheckInputs каждая строка сверяется с предыдущей строкой того же входа,
+// и первая же строка не по порядку останавливает слияние с указанием файла.
+// Заголовок из Header записей пропускается в каждом входе, выводится заголовок первого
+func (s *Sorter) Merge(ctx context.Context, inputs []io.Reader, names []string, w io.Writer) error {
+	return s.call().merge(ctx, inputs, names, w)
+}
+
+// Сливает входы в рамках одного вызова
+func (s *Sorter) merge(ctx context.Context, inputs []io.Reader, names []string, w io.Writer) error {
+
+	var header []string
+	sources := make([]*mergeSource, len(inputs))
+	for i, r := range inputs {
+		sources[i] = s.newMergeSource(names[i], r)
+		sources[i].checkKeys = true
+
+		h, err := readHeader(sources[i].r, s.Header)
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			header = h
+		}
+	}
+	if err := s.resolveKeyNames(header); err != nil {
+		return err
+	}
+
+	h, err := s.startMerge(sources, s.CheckInputs)
+	if err != nil {
+		return err
+	}
+
+	// Окончания строк берем у первого непустого входа
+	eol := s.recordSep()
+	for _, src := range sources {
+		if src.r.read {
+			eol = src.r.eol()
+			break
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := s.writeHeader(bw, eol, header); err != nil {
+		return err
+	}
+	sink := s.newLineSink(bw, eol)
+	if err := s.mergeSources(ctx, h, sink, s.CheckInputs); err != nil {
+		return err
+	}
+
+	var bad SortErrors
+	for _, src := range sources {
+		bad = append(bad, src.bad...)
+	}
+	if len(bad) > 0 {
+		return bad
+	}
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Отсортированный поток строк для слияния
+type mergeSource struct {
+	name   string
+	r      *recordReader
+	line   string // текущая строка
+	lineNo int    // номер текущей строки во входе
+	ok     bool   // текущая строка уже прочитана
+
+	checkKeys bool       // проверять ключи каждой прочитанной строки
+	bad       SortErrors // строки с неразобранными ключами, пропущенные при CollectErrors
+}
+
+func (s *Sorter) newMergeSource(name string, r io.Reader) *mergeSource {
+	return &mergeSource{name: name, r: s.newRecordReader(r)}
+}
+
+// Читает следующую непустую строку. При verify проверяет, что она не меньше текущей
+func (src *mergeSource) next(s *Sorter, verify bool) error {
+	for {
+		line, err := src.r.next()
+		if err != nil {
+			return err
+		}
+		src.lineNo = src.r.start
+		if s.skipBlank(line) { // пустые строки пропускаются, как и при чтении всего входа
+			continue
+		}
+		if src.checkKeys {
+			if err := s.checkLineKeys(line, src.lineNo); err != nil {
+				err.File = src.name
+				switch {
+				case s.BadLines == BadLineQuarantine:
+					if err := s.rejectLine(line); err != nil {
+						return err
+					}
+				case s.CollectErrors:
+					src.bad = append(src.bad, err)
+				default:
+					return err
+				}
+				continue
+			}
+		}
+
+		if verify && src.ok && s.compareLinesB(src.line, line) > 0 {
+			return &DisorderError{File: src.name, Line: src.lineNo, Text: line}
+		}
+		src.line, src.ok = line, true
+		return nil
+	}
+}
+
+// Кладет в кучу слияния по первой строке из каждого потока
+func (s *Sorter) startMerge(sources []*mergeSource, verify bool) (*mergeHeap, error) {
+	h := &mergeHeap{s: s}
+	for i, src := range sources {
+		err := src.next(s, verify)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.items = append(h.items, mergeItem{src: src, idx: i})
+	}
+	heap.Init(h)
+	return h, nil
+}
+
+// k-путевое слияние отсортированных потоков строк через кучу, начатое startMerge
+func (s *Sorter) mergeSources(ctx context.Context, h *mergeHeap, out lineSink, verify bool) error {
+	for n := 1; h.Len() > 0; n++ {
+		if s.err != nil {
+			return s.err
+		}
+		if err := canceled(ctx, n); err != nil {
+			return err
+		}
+
+		top := h.items[0].src
+		if err := out.WriteLine(top.line); err != nil {
+			return err
+		}
+
+		// Заменяем вершину следующей строкой из того же потока
+		err := top.next(s, verify)
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			heap.Fix(h, 0)
+		}
+	}
+
+	return s.err
+}
+
+// Поток слияния и его номер
+type mergeItem struct {
+	src *mergeSource
+	idx int
+}
+
+// Куча для слияния, упорядоченная компаратором Sorter
+type mergeHeap struct {
+	items []mergeItem
+	s     *Sorter
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	c := h.s.compareLinesB(h.items[i].src.line, h.items[j].src.line)
+	if c == 0 { // при равенстве берем более ранний поток, чтобы слияние было устойчивым
+		return h.items[i].idx < h.items[j].idx
+	}
+	return c < 0
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x any) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
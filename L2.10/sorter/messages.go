@@ -0,0 +1,246 @@
+package sorter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Language — язык сообщений об ошибках
+type Language string
+
+const (
+	English Language = "en"
+	Russian Language = "ru"
+)
+
+// LanguageFromEnv выбирает язык по LC_ALL, LC_MESSAGES или LANG: ru_RU.UTF-8 — русский,
+// любая другая локаль — английский
+func LanguageFromEnv() Language {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			if strings.HasPrefix(strings.ToLower(v), "ru") {
+				return Russian
+			}
+			return English
+		}
+	}
+	return English
+}
+
+// Идентификатор сообщения в каталоге
+type message int
+
+const (
+	msgLine message = iota
+	msgField
+	msgPath
+	msgBadKey
+	msgNotNumber
+	msgMissingField
+	msgNotJSON
+	msgNotDate
+
+	// причины ошибок и ошибки разбора настроек
+	msgInvalidKey
+	msgInvalidOption
+	msgFewerFields
+	msgConversion
+	msgLineNotJSON
+	msgLineNotDate
+	msgFewerFieldsThan
+	msgKeyNameNoHeader
+	msgKeyNoColumn
+	msgKeyColumnOrder
+	msgKeyBadStart
+	msgKeyBadStartChar
+	msgKeyBadEnd
+	msgKeyBadEndChar
+	msgKeyEmptyName
+	msgUnknownModifier
+	msgModifierConflict
+	msgBadNumber
+	msgUnknownSuffix
+	msgIntOverflow
+	msgBadBufferSize
+	msgBufferNotPositive
+	msgThousandsIsDecimal
+	msgPointWithComma
+	msgTempFile
+	msgUniquePolicy
+	msgEmptyUniquePolicy
+	msgBadLinePolicy
+	msgUnknownEncoding
+	msgEmptyRandomSource
+	msgNegativeParallel
+	msgUnknownLanguage
+	msgEmptyRecordSep
+	msgNegativeHeader
+	msgPathNoDot
+	msgPathNoBracket
+	msgPathBadIndex
+	msgPathEmptyKey
+	msgPathDotAfterIndex
+	msgUnknownMonthLang
+	msgUnknownLocale
+	msgEmptyDateLayout
+	msgUnknownTimeZone
+	msgDisorder
+)
+
+// Каталог сообщений: для каждого языка строка формата на каждый идентификатор
+var catalog = map[Language]map[message]string{
+	English: {
+		msgLine:         "line %d",
+		msgField:        "field %d",
+		msgPath:         "path %s",
+		msgBadKey:       "bad key: '%s'",
+		msgNotNumber:    "not a number: '%s'",
+		msgMissingField: "no such field in line '%s'",
+		msgNotJSON:      "not valid JSON: '%s'",
+		msgNotDate:      "not a date: '%s'",
+
+		msgInvalidKey:         "invalid key",
+		msgInvalidOption:      "invalid option",
+		msgFewerFields:        "line has fewer fields",
+		msgConversion:         "conversion failed",
+		msgLineNotJSON:        "line is not JSON",
+		msgLineNotDate:        "line is not a date",
+		msgFewerFieldsThan:    "%w than k=%d: '%s'",
+		msgKeyNameNoHeader:    "%w '%s': columns can be named only with a header",
+		msgKeyNoColumn:        "%w '%s': no column '%s' in the header",
+		msgKeyColumnOrder:     "%w '%s': column '%s' is left of '%s'",
+		msgKeyBadStart:        "%w '%s': invalid start",
+		msgKeyBadStartChar:    "%w '%s': invalid start character",
+		msgKeyBadEnd:          "%w '%s': invalid end",
+		msgKeyBadEndChar:      "%w '%s': invalid end character",
+		msgKeyEmptyName:       "%w '%s': empty column name",
+		msgUnknownModifier:    "unknown modifier '%c'",
+		msgModifierConflict:   "modifiers n, g, h, M, V, D and R are incompatible",
+		msgBadNumber:          "invalid number '%s'",
+		msgUnknownSuffix:      "unknown suffix '%s'",
+		msgIntOverflow:        "value does not fit in int",
+		msgBadBufferSize:      "%w: invalid buffer size '%s': %w",
+		msgBufferNotPositive:  "%w: buffer size must be positive: '%s'",
+		msgThousandsIsDecimal: "%w: thousands separator is the same as the decimal separator",
+		msgPointWithComma:     "decimal point in a number with decimal comma",
+		msgTempFile:           "cannot create temporary file: %w",
+		msgUniquePolicy:       "-u policy must be first, last or a key: %w",
+		msgEmptyUniquePolicy:  "%w: empty -u policy",
+		msgBadLinePolicy:      "%w: bad lines policy must be strict, lenient or quarantine: '%s'",
+		msgUnknownEncoding:    "%w: unknown encoding '%s'",
+		msgEmptyRandomSource:  "%w: empty random source '%s'",
+		msgNegativeParallel:   "%w: number of threads must not be negative: %d",
+		msgUnknownLanguage:    "%w: unknown language '%s'",
+		msgEmptyRecordSep:     "%w: empty record separator",
+		msgNegativeHeader:     "%w: negative number of header lines %d",
+		msgPathNoDot:          "JSON path '%s' must start with a dot",
+		msgPathNoBracket:      "JSON path '%s': missing closing bracket",
+		msgPathBadIndex:       "JSON path '%s': invalid index '%s'",
+		msgPathEmptyKey:       "JSON path '%s': empty key name",
+		msgPathDotAfterIndex:  "JSON path '%s': dot expected after index",
+		msgUnknownMonthLang:   "%w: unknown month language '%s'",
+		msgUnknownLocale:      "%w: unknown locale '%s'",
+		msgEmptyDateLayout:    "%w: empty date layout",
+		msgUnknownTimeZone:    "%w: unknown time zone '%s'",
+		msgDisorder:           "%s:%d: disorder: %s",
+	},
+	Russian: {
+		msgLine:         "строка %d",
+		msgField:        "поле %d",
+		msgPath:         "путь %s",
+		msgBadKey:       "некорректный ключ: '%s'",
+		msgNotNumber:    "не число: '%s'",
+		msgMissingField: "в строке нет такого поля: '%s'",
+		msgNotJSON:      "не JSON: '%s'",
+		msgNotDate:      "не дата: '%s'",
+
+		msgInvalidKey:         "некорректный ключ",
+		msgInvalidOption:      "некорректная настройка",
+		msgFewerFields:        "строка имеет меньше столбцов",
+		msgConversion:         "ошибка преобразования",
+		msgLineNotJSON:        "строка не JSON",
+		msgLineNotDate:        "строка не дата",
+		msgFewerFieldsThan:    "%w, чем k=%d: '%s'",
+		msgKeyNameNoHeader:    "%w '%s': столбец по имени можно задать только с заголовком",
+		msgKeyNoColumn:        "%w '%s': нет столбца '%s' в заголовке",
+		msgKeyColumnOrder:     "%w '%s': столбец '%s' левее '%s'",
+		msgKeyBadStart:        "%w '%s': неверное начало",
+		msgKeyBadStartChar:    "%w '%s': неверный символ начала",
+		msgKeyBadEnd:          "%w '%s': неверный конец",
+		msgKeyBadEndChar:      "%w '%s': неверный символ конца",
+		msgKeyEmptyName:       "%w '%s': пустое имя столбца",
+		msgUnknownModifier:    "неизвестный модификатор '%c'",
+		msgModifierConflict:   "модификаторы n, g, h, M, V, D и R несовместимы",
+		msgBadNumber:          "некорректное число '%s'",
+		msgUnknownSuffix:      "неизвестный суффикс '%s'",
+		msgIntOverflow:        "значение не помещается в int",
+		msgBadBufferSize:      "%w: некорректный размер буфера '%s': %w",
+		msgBufferNotPositive:  "%w: размер буфера должен быть положительным: '%s'",
+		msgThousandsIsDecimal: "%w: разделитель разрядов совпадает с десятичным разделителем",
+		msgPointWithComma:     "точка в числе с десятичной запятой",
+		msgTempFile:           "не удалось создать временный файл: %w",
+		msgUniquePolicy:       "политика -u должна быть first, last или ключом: %w",
+		msgEmptyUniquePolicy:  "%w: пустая политика -u",
+		msgBadLinePolicy:      "%w: политика плохих строк должна быть strict, lenient или quarantine: '%s'",
+		msgUnknownEncoding:    "%w: неизвестная кодировка '%s'",
+		msgEmptyRandomSource:  "%w: пустой источник случайности '%s'",
+		msgNegativeParallel:   "%w: число потоков должно быть неотрицательным: %d",
+		msgUnknownLanguage:    "%w: неизвестный язык '%s'",
+		msgEmptyRecordSep:     "%w: пустой разделитель записей",
+		msgNegativeHeader:     "%w: отрицательное число строк заголовка %d",
+		msgPathNoDot:          "путь JSON '%s' должен начинаться с точки",
+		msgPathNoBracket:      "путь JSON '%s': нет закрывающей скобки",
+		msgPathBadIndex:       "путь JSON '%s': неверный индекс '%s'",
+		msgPathEmptyKey:       "путь JSON '%s': пустое имя ключа",
+		msgPathDotAfterIndex:  "путь JSON '%s': после индекса нужна точка",
+		msgUnknownMonthLang:   "%w: неизвестный язык месяцев '%s'",
+		msgUnknownLocale:      "%w: неизвестная локаль '%s'",
+		msgEmptyDateLayout:    "%w: пустая раскладка даты",
+		msgUnknownTimeZone:    "%w: неизвестный часовой пояс '%s'",
+		msgDisorder:           "%s:%d: нарушен порядок: %s",
+	},
+}
+
+// Строка формата сообщения на языке l. Незнакомый язык — английский
+func (l Language) format(id message) string {
+	msgs, ok := catalog[l]
+	if !ok {
+		msgs = catalog[English]
+	}
+	return msgs[id]
+}
+
+// Форматирует сообщение на языке l
+func (l Language) sprintf(id message, args ...any) string {
+	return fmt.Sprintf(l.format(id), args...)
+}
+
+// Создает ошибку с сообщением на языке окружения, %w в сообщении оборачивает ошибку, как в fmt.Errorf
+func errorf(id message, args ...any) error {
+	return fmt.Errorf(LanguageFromEnv().format(id), args...)
+}
+
+// Ошибка-причина с текстом из каталога. Язык выбирается при выводе, а не при создании,
+// поэтому сентинели пакета следуют окружению
+type reasonError message
+
+func (e reasonError) Error() string {
+	return LanguageFromEnv().sprintf(message(e))
+}
+
+// Сообщение для причины ошибки ключа
+func reasonMessage(reason error) message {
+	switch reason {
+	case ErrNotNumber:
+		return msgNotNumber
+	case ErrMissingField:
+		return msgMissingField
+	case ErrNotJSON:
+		return msgNotJSON
+	case ErrNotDate:
+		return msgNotDate
+	default:
+		return msgBadKey
+	}
+}
@@ -0,0 +1,78 @@
+package sorter
+
+import (
+	"strings"
+)
+
+// MonthTable сопоставляет названия месяцев в нижнем регистре их номерам с 1 до 12
+type MonthTable map[string]int
+
+// NewMonthTable собирает таблицу из всех форм названия каждого месяца, с января по декабрь.
+// Регистр не важен: формы приводятся к нижнему
+func NewMonthTable(names [12][]string) MonthTable {
+	t := make(MonthTable)
+	for i, forms := range names {
+		for _, f := range forms {
+			t[strings.ToLower(f)] = i + 1
+		}
+	}
+	return t
+}
+
+// Встроенные таблицы месяцев: полные названия и сокращения, для русского — и в родительном падеже
+var (
+	EnglishMonths = NewMonthTable([12][]string{
+		{"January", "Jan"}, {"February", "Feb"}, {"March", "Mar"}, {"April", "Apr"},
+		{"May"}, {"June", "Jun"}, {"July", "Jul"}, {"August", "Aug"},
+		{"September", "Sep", "Sept"}, {"October", "Oct"}, {"November", "Nov"}, {"December", "Dec"},
+	})
+	RussianMonths = NewMonthTable([12][]string{
+		{"январь", "января", "янв"},
+		{"февраль", "февраля", "фев", "февр"},
+		{"март", "марта", "мар"},
+		{"апрель", "апреля", "апр"},
+		{"май", "мая"},
+		{"июнь", "июня", "июн"},
+		{"июль", "июля", "июл"},
+		{"август", "августа", "авг"},
+		{"сентябрь", "сентября", "сен", "сент"},
+		{"октябрь", "октября", "окт"},
+		{"ноябрь", "ноября", "ноя", "нояб"},
+		{"декабрь", "декабря", "дек"},
+	})
+)
+
+// Таблицы месяцев по имени языка для ParseMonthTables
+var monthTables = map[string]MonthTable{
+	"en": EnglishMonths,
+	"ru": RussianMonths,
+}
+
+// ParseMonthTables разбирает список языков месяцев через запятую: en, ru или en,ru
+func ParseMonthTables(list string) ([]MonthTable, error) {
+	var tables []MonthTable
+	for name := range strings.SplitSeq(list, ",") {
+		t, ok := monthTables[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, errorf(msgUnknownMonthLang, ErrInvalidOption, name)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// Номер месяца по названию: без учета регистра, пробелов по краям и точки сокращения.
+// Неизвестное значение — 0, как в GNU sort оно идет раньше января
+func (s *Sorter) month(text string) int {
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(text), "."))
+	tables := s.MonthTables
+	if len(tables) == 0 {
+		tables = []MonthTable{EnglishMonths, RussianMonths}
+	}
+	for _, t := range tables {
+		if m, ok := t[name]; ok {
+			return m
+		}
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package sorter
+
+import (
+	"cmp"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Разбирает число для -g: дроби, экспоненту, inf и nan.
+// Разделитель разрядов выкидывается, десятичная запятая заменяется на точку
+func (s *Sorter) parseGeneralNumeric(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+
+	if s.ThousandsSep != "" {
+		text = strings.ReplaceAll(text, s.ThousandsSep, "")
+	}
+
+	if s.DecimalComma {
+		if strings.Contains(text, ".") {
+			return 0, errorf(msgPointWithComma)
+		}
+		text = strings.Replace(text, ",", ".", 1)
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) { // переполнение дает ±Inf, это валидное значение
+		return 0, err
+	}
+	return f, nil
+}
+
+// Сравнение дробных чисел с реверсом.
+// Порядок как в GNU sort -g: NaN < -Inf < числа < +Inf, -0 и +0 равны
+func compareFloats(a, b float64, reverse bool) int {
+	if reverse {
+		return cmp.Compare(b, a)
+	}
+	return cmp.Compare(a, b)
+}
@@ -0,0 +1,340 @@
+package sorter
+
+import (
+	"io"
+	"os"
+)
+
+// Option настраивает Sorter при создании через New
+type Option func(*Sorter) error
+
+// New создает Sorter с быстрым методом сортировки SortA, применяет опции по порядку
+// и проверяет их сочетание через Validate
+func New(opts ...Option) (*Sorter, error) {
+	s := &Sorter{SortType: true, TempDir: os.TempDir()}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate проверяет настройки, которые нельзя проверить по отдельности, и возвращает
+// ошибку с ErrInvalidOption. Вызывается из New, а при заполнении полей напрямую — вызывающим
+func (s *Sorter) Validate() error {
+	if s.DecimalComma && s.ThousandsSep == "," || !s.DecimalComma && s.ThousandsSep == "." {
+		return errorf(msgThousandsIsDecimal, ErrInvalidOption)
+	}
+	return nil
+}
+
+// WithKeys задает ключи в формате -k: "2,2n", "1,1r"
+func WithKeys(specs ...string) Option {
+	return func(s *Sorter) error {
+		for _, spec := range specs {
+			k, err := ParseKeySpec(spec)
+			if err != nil {
+				return err
+			}
+			s.Keys = append(s.Keys, k)
+		}
+		return nil
+	}
+}
+
+// WithKeySpecs задает уже разобранные ключи
+func WithKeySpecs(keys ...KeySpec) Option {
+	return func(s *Sorter) error {
+		s.Keys = append(s.Keys, keys...)
+		return nil
+	}
+}
+
+// WithSeparator задает разделитель полей, пустая строка — серии пробелов
+func WithSeparator(sep string) Option {
+	return func(s *Sorter) error {
+		s.Separator = sep
+		s.BlankFields = sep == ""
+		return nil
+	}
+}
+
+// WithNumeric сравнивает ключи как целые числа (-n)
+func WithNumeric() Option {
+	return func(s *Sorter) error {
+		s.Numeric = true
+		return nil
+	}
+}
+
+// WithGeneralNumeric сравнивает ключи как числа с плавающей точкой (-g)
+func WithGeneralNumeric() Option {
+	return func(s *Sorter) error {
+		s.GeneralNumeric = true
+		return nil
+	}
+}
+
+// WithDecimalComma разбирает дробную часть чисел после запятой: 3,14 (--decimal-comma)
+func WithDecimalComma() Option {
+	return func(s *Sorter) error {
+		s.DecimalComma = true
+		return nil
+	}
+}
+
+// WithThousandsSep выкидывает разделитель разрядов перед разбором чисел -g (--thousands-sep)
+func WithThousandsSep(sep string) Option {
+	return func(s *Sorter) error {
+		s.ThousandsSep = sep
+		return nil
+	}
+}
+
+// WithHumanReadable сравнивает ключи как размеры 2K, 1.5G (-h)
+func WithHumanReadable() Option {
+	return func(s *Sorter) error {
+		s.HumanReadable = true
+		return nil
+	}
+}
+
+// WithMonth сравнивает ключи как месяцы (-M). Без таблиц понимает английские и русские названия
+func WithMonth(tables ...MonthTable) Option {
+	return func(s *Sorter) error {
+		s.MonthCheck = true
+		s.MonthTables = append(s.MonthTables, tables...)
+		return nil
+	}
+}
+
+// WithDate сравнивает ключи как даты. Раскладки — имена вроде rfc3339, clf, unix
+// или раскладки Go, без них пробуются распространенные форматы
+func WithDate(layouts ...string) Option {
+	return func(s *Sorter) error {
+		s.Date = true
+		for _, name := range layouts {
+			layout, err := ParseDateLayout(name)
+			if err != nil {
+				return err
+			}
+			s.DateLayouts = append(s.DateLayouts, layout)
+		}
+		return nil
+	}
+}
+
+// WithTimeZone задает пояс для дат без пояса: Europe/Moscow, +03:00
+func WithTimeZone(name string) Option {
+	return func(s *Sorter) error {
+		loc, err := ParseTimeZone(name)
+		if err != nil {
+			return err
+		}
+		s.TimeZone = loc
+		return nil
+	}
+}
+
+// WithVersion сравнивает ключи как версии (-V)
+func WithVersion() Option {
+	return func(s *Sorter) error {
+		s.VersionSort = true
+		return nil
+	}
+}
+
+// WithReverse сортирует в обратном порядке (-r)
+func WithReverse() Option {
+	return func(s *Sorter) error {
+		s.Reverse = true
+		return nil
+	}
+}
+
+// WithStable сохраняет порядок строк с равными ключами (-s)
+func WithStable() Option {
+	return func(s *Sorter) error {
+		s.Stable = true
+		return nil
+	}
+}
+
+// WithUnique оставляет одну строку из группы с равными ключами (-u)
+func WithUnique() Option {
+	return func(s *Sorter) error {
+		s.Unique = true
+		return nil
+	}
+}
+
+// WithFoldCase сравнивает строки без учета регистра (-f)
+func WithFoldCase() Option {
+	return func(s *Sorter) error {
+		s.FoldCase = true
+		return nil
+	}
+}
+
+// WithCollation задает правила сравнения строк по имени локали: C, en, ru
+func WithCollation(locale string) Option {
+	return func(s *Sorter) error {
+		c, err := ParseCollation(locale)
+		if err != nil {
+			return err
+		}
+		s.Collation = c
+		return nil
+	}
+}
+
+// WithParallel задает число потоков сортировки
+func WithParallel(n int) Option {
+	return func(s *Sorter) error {
+		if n < 0 {
+			return errorf(msgNegativeParallel, ErrInvalidOption, n)
+		}
+		s.Parallel = n
+		return nil
+	}
+}
+
+// WithBufferSize включает внешнюю сортировку с лимитом памяти вида 100M (-S)
+func WithBufferSize(size string) Option {
+	return func(s *Sorter) error {
+		n, err := ParseBufferSize(size)
+		if err != nil {
+			return err
+		}
+		s.BufferSize = n
+		return nil
+	}
+}
+
+// WithTempDir задает каталог для временных файлов внешней сортировки (-T)
+func WithTempDir(dir string) Option {
+	return func(s *Sorter) error {
+		s.TempDir = dir
+		return nil
+	}
+}
+
+// WithCollectErrors собирает все строки с неразобранными ключами в SortErrors
+func WithCollectErrors() Option {
+	return func(s *Sorter) error {
+		s.CollectErrors = true
+		return nil
+	}
+}
+
+// WithLanguage задает язык сообщений об ошибках: en или ru
+func WithLanguage(lang Language) Option {
+	return func(s *Sorter) error {
+		if _, ok := catalog[lang]; !ok {
+			return errorf(msgUnknownLanguage, ErrInvalidOption, lang)
+		}
+		s.Language = lang
+		return nil
+	}
+}
+
+// WithLenient не останавливает сортировку на плохих строках: неразобранный ключ
+// меньше любого значения, пропавшее поле — пустой ключ
+func WithLenient() Option {
+	return func(s *Sorter) error {
+		s.BadLines = BadLineLenient
+		return nil
+	}
+}
+
+// WithQuarantine пишет строки с неразобранными ключами в reject и сортирует остальные
+func WithQuarantine(reject io.Writer) Option {
+	return func(s *Sorter) error {
+		s.BadLines = BadLineQuarantine
+		s.Reject = reject
+		return nil
+	}
+}
+
+// WithSkipBlanks пропускает пробелы в начале полей ключа (-b)
+func WithSkipBlanks() Option {
+	return func(s *Sorter) error {
+		s.SkipBlanks = true
+		return nil
+	}
+}
+
+// WithRecordSep задает разделитель записей вместо перевода строки, "\x00" — как -z
+func WithRecordSep(sep string) Option {
+	return func(s *Sorter) error {
+		if sep == "" {
+			return errorf(msgEmptyRecordSep, ErrInvalidOption)
+		}
+		s.RecordSep = sep
+		return nil
+	}
+}
+
+// WithKeepBlank оставляет пустые строки, они идут раньше остальных
+func WithKeepBlank() Option {
+	return func(s *Sorter) error {
+		s.KeepBlank = true
+		return nil
+	}
+}
+
+// WithCSV читает записи как CSV по RFC 4180 с разделителем полей sep, пустой — запятая
+func WithCSV(sep string) Option {
+	return func(s *Sorter) error {
+		s.CSV = true
+		s.Separator = sep
+		return nil
+	}
+}
+
+// WithHeader оставляет первые n записей заголовком: они выводятся первыми без сортировки
+func WithHeader(n int) Option {
+	return func(s *Sorter) error {
+		if n < 0 {
+			return errorf(msgNegativeHeader, ErrInvalidOption, n)
+		}
+		s.Header = n
+		return nil
+	}
+}
+
+// WithJSON читает записи как JSON Lines: ключи задаются путями JSON, строки выводятся как есть
+func WithJSON() Option {
+	return func(s *Sorter) error {
+		s.JSON = true
+		return nil
+	}
+}
+
+// WithRandom сортирует в случайном порядке по хешу ключа, равные ключи остаются рядом (-R)
+func WithRandom() Option {
+	return func(s *Sorter) error {
+		s.Random = true
+		return nil
+	}
+}
+
+// WithShuffle перемешивает строки без сортировки, при -u — после схлопывания дубликатов
+func WithShuffle() Option {
+	return func(s *Sorter) error {
+		s.Shuffle = true
+		return nil
+	}
+}
+
+// WithSeed задает зерно для -R и перемешивания, чтобы порядок повторялся от запуска к запуску
+func WithSeed(seed []byte) Option {
+	return func(s *Sorter) error {
+		s.RandomSeed = seed
+		return nil
+	}
+}
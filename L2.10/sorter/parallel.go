@@ -0,0 +1,122 @@
+package sorter
+
+import (
+	"sync"
+)
+
+// Меньше стольких строк на поток параллелить нет смысла: накладные расходы больше выигрыша
+const minParallelChunk = 4096
+
+// Сколько потоков реально использовать для n элементов
+func (s *Sorter) workers(n int) int {
+	w := min(s.Parallel, n/minParallelChunk)
+	if w < 2 {
+		return 1
+	}
+	return w
+}
+
+// Делит [0, n) на w почти равных диапазонов
+func splitRanges(n, w int) [][2]int {
+	ranges := make([][2]int, w)
+	for i := range w {
+		ranges[i] = [2]int{i * n / w, (i + 1) * n / w}
+	}
+	return ranges
+}
+
+// Параллельно строит ключи строк. Возвращает те же ошибки, что и последовательный
+// вариант: первую из самого раннего диапазона или, при CollectErrors, все по порядку строк
+func (s Sorter) buildSortableLinesParallel(lines []string, w int) ([]sortableLine, error) {
+	specs := s.keys()
+	res := make([]sortableLine, len(lines))
+	keys := make([]sortKey, len(lines)*len(specs))
+	errs := make([]SortErrors, w)
+
+	var wg sync.WaitGroup
+	for r, bounds := range splitRanges(len(lines), w) {
+		wg.Go(func() {
+			for i := bounds[0]; i < bounds[1]; i++ {
+				lineKeys := keys[i*len(specs) : (i+1)*len(specs)]
+				if err := s.buildLineKeys(specs, lines[i], i+1, lineKeys); err != nil {
+					errs[r] = append(errs[r], err)
+					if !s.CollectErrors {
+						return
+					}
+				}
+				res[i] = sortableLine{line: lines[i], keys: lineKeys}
+			}
+		})
+	}
+	wg.Wait()
+
+	var all SortErrors
+	for _, rangeErrs := range errs {
+		if len(rangeErrs) > 0 && !s.CollectErrors {
+			return nil, rangeErrs[0]
+		}
+		all = append(all, rangeErrs...)
+	}
+	if len(all) > 0 {
+		return nil, all
+	}
+	return res, nil
+}
+
+// Сортирует x в w потоков: каждый поток сортирует свой кусок, затем куски
+// попарно сливаются, тоже параллельно. При равенстве берется элемент из более
+// раннего куска, поэтому при stable результат совпадает с последовательной сортировкой
+func parallelSort[E any](x []E, w int, stable bool, cmp func(a, b E) int) {
+	ranges := splitRanges(len(x), w)
+
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		wg.Go(func() {
+			sortSlice(x[r[0]:r[1]], stable, cmp)
+		})
+	}
+	wg.Wait()
+
+	src, dst := x, make([]E, len(x))
+	for len(ranges) > 1 {
+		var merged [][2]int
+		for i := 0; i < len(ranges); i += 2 {
+			if i+1 == len(ranges) { // Непарный кусок просто переносим
+				r := ranges[i]
+				wg.Go(func() { copy(dst[r[0]:r[1]], src[r[0]:r[1]]) })
+				merged = append(merged, r)
+				continue
+			}
+			a, b := ranges[i], ranges[i+1]
+			wg.Go(func() {
+				mergeRuns(dst[a[0]:b[1]], src[a[0]:a[1]], src[b[0]:b[1]], cmp)
+			})
+			merged = append(merged, [2]int{a[0], b[1]})
+		}
+		wg.Wait()
+
+		src, dst = dst, src
+		ranges = merged
+	}
+
+	if &src[0] != &x[0] { // Итог оказался во вспомогательном буфере
+		copy(x, src)
+	}
+}
+
+// Сливает два отсортированных куска в dst, при равенстве первым идет элемент из a
+func mergeRuns[E any](dst, a, b []E, cmp func(a, b E) int) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if cmp(b[j], a[i]) < 0 {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
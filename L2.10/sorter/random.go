@@ -0,0 +1,127 @@
+package sorter
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+)
+
+// Сколько байт зерна читается из --random-source
+const seedSize = 32
+
+// ReadSeed читает зерно для -R и --shuffle из файла, как --random-source в GNU sort.
+// Берутся первые 32 байта, файл короче тоже подходит, но не пустой
+func ReadSeed(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seed := make([]byte, seedSize)
+	n, err := io.ReadFull(f, seed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return nil, errorf(msgEmptyRandomSource, ErrInvalidOption, path)
+		}
+		return nil, err
+	}
+	return seed[:n], nil
+}
+
+// Использует ли сортировка случайность: -R, --shuffle или ключ с R
+func (s *Sorter) usesRandom() bool {
+	if s.Random || s.Shuffle {
+		return true
+	}
+	for _, k := range slices.Concat(s.Keys, s.UniqueBy) {
+		if k.Random {
+			return true
+		}
+	}
+	return false
+}
+
+// Выбирает зерно вызова: RandomSeed, а если он не задан и случайность нужна — новое.
+// Зерно выбирается один раз до сортировки, чтобы все сравнения и куски внешней
+// сортировки видели один и тот же хеш. RandomSeed не меняется, поэтому повторный
+// вызов без него дает новый порядок
+func (s *Sorter) resetSeed() {
+	s.seed = s.RandomSeed
+	if s.seed == nil && s.usesRandom() {
+		s.seed = make([]byte, seedSize)
+		rand.Read(s.seed)
+	}
+}
+
+// Ключевой хеш для -R: FNV-1a по зерну и ключу с перемешиванием битов из MurmurHash3.
+// Равные ключи дают равный хеш, поэтому строки с ними остаются рядом
+func randomHash(seed []byte, key string) int {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	for _, c := range seed {
+		h = (h ^ uint64(c)) * prime
+	}
+	for i := 0; i < len(key); i++ {
+		h = (h ^ uint64(key[i])) * prime
+	}
+
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return int(h)
+}
+
+// Перемешивает строки тасованием Фишера — Йетса с генератором от зерна
+func (s *Sorter) shuffleLines(lines []string) {
+	rng := mrand.New(mrand.NewChaCha8(sha256.Sum256(s.seed)))
+	for i := len(lines) - 1; i > 0; i-- {
+		j := rng.IntN(i + 1)
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+}
+
+// Готовит строки к выводу при Shuffle: проверяет ключи, при Unique сначала оставляет
+// по строке на группу равных ключей, затем перемешивает. Зерно уже выбрано в Sort,
+// копия для сортировки берет его же
+func (s *Sorter) shuffle(lines []string) ([]string, error) {
+	if s.Unique {
+		sorted := *s
+		sorted.Shuffle = false
+		if err := sorted.sortLines(lines); err != nil {
+			return nil, err
+		}
+
+		var kept collectSink
+		sink := &uniqueSink{s: &sorted, out: &kept}
+		for _, line := range lines {
+			if err := sink.WriteLine(line); err != nil {
+				return nil, err
+			}
+		}
+		if err := sink.Close(); err != nil {
+			return nil, err
+		}
+		lines = kept
+	} else if err := s.checkKeys(lines); err != nil {
+		return nil, err
+	}
+
+	s.shuffleLines(lines)
+	return lines, nil
+}
+
+// Собирает строки в срез
+type collectSink []string
+
+func (c *collectSink) WriteLine(line string) error {
+	*c = append(*c, line)
+	return nil
+}
+
+func (c *collectSink) Close() error { return nil }
@@ -0,0 +1,1950 @@
+package sorter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestSortByColumn(t *testing.T) {
+	input := []string{
+		"b\t2",
+		"a\t1",
+		"c\t3",
+	}
+	want := []string{
+		"a\t1",
+		"b\t2",
+		"c\t3",
+	}
+
+	s := Sorter{Column: 2} // сортируем по 2 колонке
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("at %d expected %q, got %q", i, want[i], input[i])
+		}
+	}
+}
+
+func TestNumericSort(t *testing.T) {
+	input := []string{"10", "2", "1"}
+	want := []string{"1", "2", "10"}
+
+	s := Sorter{Numeric: true, Column: 1}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, input)
+		}
+	}
+}
+
+func TestReverseSort(t *testing.T) {
+	input := []string{"a", "b", "c"}
+	want := []string{"c", "b", "a"}
+
+	s := Sorter{Reverse: true, Column: 1}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, input)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	input := []string{"a", "a", "b", "b", "c"}
+	want := []string{"a", "b", "c"}
+
+	got, err := uniqueLines(&Sorter{Unique: true}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+// Пропускает отсортированные строки через приемник -u и возвращает результат
+func uniqueLines(s *Sorter, lines []string) ([]string, error) {
+	var out strings.Builder
+	bw := bufio.NewWriter(&out)
+	sink := s.newLineSink(bw, "\n")
+	for _, line := range lines {
+		if err := sink.WriteLine(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return nil, err
+	}
+	bw.Flush()
+	if out.Len() == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), nil
+}
+
+func TestUniqueByKey(t *testing.T) {
+	input := []string{"a\t1\tx", "b\t2\tz", "c\t1\ty", "d\t2\tw", "e\t1\tv"}
+
+	tests := []struct {
+		name string
+		s    Sorter
+		want []string
+	}{
+		{"first", Sorter{}, []string{"a\t1\tx", "b\t2\tz"}},
+		{"last", Sorter{UniqueKeep: KeepLast}, []string{"e\t1\tv", "d\t2\tw"}},
+		{"best", Sorter{UniqueKeep: KeepBest, UniqueBy: []KeySpec{{StartField: 3, EndField: 3}}}, []string{"e\t1\tv", "d\t2\tw"}},
+		{"count", Sorter{CountDuplicates: true}, []string{"      3 a\t1\tx", "      2 b\t2\tz"}},
+	}
+
+	for _, tt := range tests {
+		s := tt.s
+		s.Unique = true
+		s.Keys = []KeySpec{{StartField: 2, EndField: 2, Numeric: true}}
+
+		for _, sortType := range []bool{true, false} {
+			s.SortType = sortType
+			lines := slices.Clone(input)
+			if err := s.SortLines(lines); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			got, err := uniqueLines(&s, lines)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s, SortType=%t: expected %q, got %q", tt.name, sortType, tt.want, got)
+			}
+		}
+	}
+}
+
+// На входе длиннее 12 строк sort уже не сортирует вставками, поэтому
+// без стабильной сортировки -u оставил бы произвольный дубликат
+func TestUniqueKeepsInputOrder(t *testing.T) {
+	var input []string
+	for i := range 200 {
+		input = append(input, fmt.Sprintf("%d\tline%d", i%3, i))
+	}
+
+	tests := []struct {
+		name string
+		keep UniquePolicy
+		want []string
+	}{
+		{"first", KeepFirst, []string{"0\tline0", "1\tline1", "2\tline2"}},
+		{"last", KeepLast, []string{"0\tline198", "1\tline199", "2\tline197"}},
+	}
+	for _, tt := range tests {
+		for _, parallel := range []int{1, 4} {
+			for _, sortType := range []bool{true, false} {
+				s := Sorter{Unique: true, UniqueKeep: tt.keep, SortType: sortType, Parallel: parallel,
+					Keys: []KeySpec{{StartField: 1, EndField: 1, Numeric: true}}}
+				lines := slices.Clone(input)
+				if err := s.SortLines(lines); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got, err := uniqueLines(&s, lines)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%s, SortType=%t, Parallel=%d: expected %q, got %q", tt.name, sortType, parallel, tt.want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestParseUniquePolicy(t *testing.T) {
+	if p, _, err := ParseUniquePolicy("last"); err != nil || p != KeepLast {
+		t.Errorf("expected KeepLast, got %v, %v", p, err)
+	}
+	p, keys, err := ParseUniquePolicy("3,3nr")
+	if err != nil || p != KeepBest || len(keys) != 1 || !keys[0].Reverse {
+		t.Errorf("expected KeepBest by 3,3nr, got %v, %+v, %v", p, keys, err)
+	}
+	if _, _, err := ParseUniquePolicy("middle"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestMonthSort(t *testing.T) {
+	input := []string{"Mar", "Jan", "Feb"}
+	want := []string{"Jan", "Feb", "Mar"}
+
+	s := Sorter{MonthCheck: true, Column: 1}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, input)
+		}
+	}
+}
+
+func TestMonthNames(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"Jan", 1},
+		{"jan", 1},
+		{" Feb", 2},
+		{"SEPTEMBER", 9},
+		{"Sept.", 9},
+		{"янв", 1},
+		{"Январь", 1},
+		{"января", 1},
+		{"мая", 5},
+		{"Дек.", 12},
+		{"abc", 0},
+		{"", 0},
+	}
+
+	var s Sorter
+	for _, tt := range tests {
+		if got := s.month(tt.text); got != tt.want {
+			t.Errorf("month(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+
+	s.MonthTables = []MonthTable{EnglishMonths}
+	if got := s.month("март"); got != 0 {
+		t.Errorf("expected Russian month unknown with English table only, got %d", got)
+	}
+	if tables, err := ParseMonthTables("en, RU"); err != nil || len(tables) != 2 {
+		t.Errorf("expected two tables, got %d, %v", len(tables), err)
+	}
+	if _, err := ParseMonthTables("de"); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+}
+
+func TestMonthSortUnknownFirst(t *testing.T) {
+	input := []string{"марта", "Feb", "???", "january", "Декабрь"}
+	want := []string{"???", "january", "Feb", "марта", "Декабрь"}
+	for _, sortType := range []bool{true, false} {
+		lines := slices.Clone(input)
+		s := Sorter{MonthCheck: true, SortType: sortType}
+		if err := s.SortLines(lines); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(lines, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, lines)
+		}
+	}
+}
+
+func TestIgnoreTrailingBlanks(t *testing.T) {
+	input := []string{"a   ", "a  ", "a "}
+	want := []string{"a   ", "a  ", "a "}
+	s := Sorter{RemoveTBlanks: true, Column: 1, Stable: true} // ключи равны, порядок сохраняется
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, input)
+		}
+	}
+}
+
+func TestStableSort(t *testing.T) {
+	for _, sortType := range []bool{true, false} {
+		var input, want []string
+		for i := range 100 { // Достаточно строк, чтобы сортировка не свелась к вставкам
+			input = append(input, fmt.Sprintf("%d\t2", i), fmt.Sprintf("%d\t1", i))
+		}
+		for i := range 100 {
+			want = append(want, fmt.Sprintf("%d\t1", i))
+		}
+		for i := range 100 {
+			want = append(want, fmt.Sprintf("%d\t2", i))
+		}
+
+		s := Sorter{Column: 2, Numeric: true, Stable: true, SortType: sortType}
+		if err := s.SortLines(input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(input, want) {
+			t.Errorf("SortType=%t: input order of equal keys was not kept", sortType)
+		}
+	}
+}
+
+func TestLastResortComparison(t *testing.T) {
+	for _, sortType := range []bool{true, false} {
+		input := []string{"c\t1", "a\t1", "b\t1"}
+		want := []string{"a\t1", "b\t1", "c\t1"}
+
+		s := Sorter{Column: 2, SortType: sortType}
+		if err := s.SortLines(input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(input, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, input)
+		}
+
+		s.Reverse = true
+		if err := s.SortLines(input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if slices.Reverse(want); !slices.Equal(input, want) {
+			t.Errorf("SortType=%t reverse: expected %q, got %q", sortType, want, input)
+		}
+	}
+}
+
+func TestHumanReadable(t *testing.T) {
+	input := []string{"1K", "512", "2K"}
+	want := []string{"512", "1K", "2K"}
+
+	s := Sorter{HumanReadable: true, Column: 1}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range want {
+		if input[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, input)
+		}
+	}
+}
+
+func TestGeneralNumericSort(t *testing.T) {
+	input := []string{"1e3", "inf", "-2.5", "3.14", "nan", "-inf", "0", "-0"}
+	want := []string{"nan", "-inf", "-2.5", "-0", "0", "3.14", "1e3", "inf"}
+
+	s := Sorter{GeneralNumeric: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestParseGeneralNumeric(t *testing.T) {
+	tests := []struct {
+		s       Sorter
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{Sorter{}, "3.14", 3.14, false},
+		{Sorter{}, " -1.5e2 ", -150, false},
+		{Sorter{DecimalComma: true}, "3,14", 3.14, false},
+		{Sorter{DecimalComma: true, ThousandsSep: " "}, "1 234,5", 1234.5, false},
+		{Sorter{ThousandsSep: ","}, "1,234,567.5", 1234567.5, false},
+		{Sorter{DecimalComma: true}, "3.14", 0, true},
+		{Sorter{}, "abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.s.parseGeneralNumeric(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseGeneralNumeric(%q) error: %v, wantErr: %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseGeneralNumeric(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// Разделитель разрядов, совпадающий с десятичным, — ошибка настройки, а не каждой строки
+func TestValidateThousandsSep(t *testing.T) {
+	for _, s := range []Sorter{{ThousandsSep: "."}, {DecimalComma: true, ThousandsSep: ","}} {
+		if err := s.Validate(); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%+v: expected ErrInvalidOption, got %v", s, err)
+		}
+	}
+	if err := (&Sorter{DecimalComma: true, ThousandsSep: "."}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := New(WithDecimalComma(), WithThousandsSep(",")); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected New to return ErrInvalidOption, got %v", err)
+	}
+}
+
+func TestHumanReadableSuffixes(t *testing.T) {
+	input := []string{"1.5G", "2T", "900", "1023M", "-1K", "10KiB", "1E", "-5", "0"}
+	want := []string{"-1K", "-5", "0", "900", "10KiB", "1023M", "1.5G", "2T", "1E"}
+
+	s := Sorter{HumanReadable: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestHumanReadableSI(t *testing.T) {
+	// 1KiB = 1024 больше 1KB = 1000 только в режиме SI
+	input := []string{"1KiB", "1KB"}
+
+	s := Sorter{HumanReadable: true, HumanSI: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input[0] != "1KB" {
+		t.Errorf("expected 1KB first, got %v", input)
+	}
+}
+
+func TestToHumanFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"2k", 2048, false},
+		{"1.5K", 1536, false},
+		{"10KiB", 10240, false},
+		{"1MB", 1024 * 1024, false},
+		{"2T", 2 << 40, false},
+		{"7E", 7 << 60, false},
+		{"8E", 0, true},
+		{"1Y", 0, true},
+		{"1X", 0, true},
+		{"K", 0, true},
+		{"1.2.3K", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := toHumanFormat(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("toHumanFormat(%q) error: %v, wantErr: %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("toHumanFormat(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVersionSort(t *testing.T) {
+	input := []string{"app-1.10.2", "file10.txt", "app-1.9.0", "file2.txt", "app-1.10.0~rc1", "app-1.10.0", "1:app-0.1"}
+	want := []string{"app-1.9.0", "app-1.10.0~rc1", "app-1.10.0", "app-1.10.2", "file2.txt", "file10.txt", "1:app-0.1"}
+
+	s := Sorter{VersionSort: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.01", "1.1", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0+", -1},
+		{"2:1.0", "1:9.9", 1},
+		{"0:1.0", "1.0", 0},
+		{"99999999999999999999", "100000000000000000000", -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRussianCollation(t *testing.T) {
+	input := []string{"яблоко", "ёж", "Ель", "елка", "apple", "Банан", "банан", "10", " пробел"}
+	want := []string{" пробел", "10", "банан", "Банан", "елка", "Ель", "ёж", "яблоко", "apple"}
+
+	for _, sortType := range []bool{true, false} {
+		got := slices.Clone(input)
+		s := Sorter{Collation: CollateRussian, SortType: sortType}
+		if err := s.SortLines(got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, got)
+		}
+	}
+}
+
+func TestEnglishCollationFoldCase(t *testing.T) {
+	input := []string{"b", "B", "a", "яблоко", "A"}
+	want := []string{"a", "A", "b", "B", "яблоко"} // при -f регистр не различается, порядок ввода сохраняется
+
+	s := Sorter{Collation: CollateEnglish, Stable: true, FoldCase: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %q, got %q", want, input)
+	}
+}
+
+func TestDictionaryAndNonPrinting(t *testing.T) {
+	input := []string{"b-c", "a.d", "ab", "a\x01c"}
+	want := []string{"ab", "a\x01c", "a.d", "b-c"}
+
+	s := Sorter{Keys: []KeySpec{{StartField: 1, Dictionary: true, IgnoreNonPrint: true}}}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %q, got %q", want, input)
+	}
+}
+
+func TestParseCollation(t *testing.T) {
+	tests := map[string]Collation{
+		"C":           CollateBytes,
+		"POSIX":       CollateBytes,
+		"ru_RU.UTF-8": CollateRussian,
+		"en_US.UTF-8": CollateEnglish,
+		"ru":          CollateRussian,
+	}
+	for name, want := range tests {
+		if got, err := ParseCollation(name); err != nil || got != want {
+			t.Errorf("ParseCollation(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseCollation("xx_YY"); err == nil {
+		t.Error("expected error for unknown locale")
+	}
+}
+
+func TestCheckSort(t *testing.T) {
+	sorted := []string{"a", "b", "c"}
+	unsorted := []string{"b", "a", "c"}
+
+	s := Sorter{Column: 1}
+	if !s.isSorted(sorted) {
+		t.Error("expected sorted slice to be sorted")
+	}
+
+	if s.isSorted(unsorted) {
+		t.Error("expected unsorted slice to be detected")
+	}
+}
+
+func TestCheckSortByColumn(t *testing.T) {
+	sorted := []string{
+		"a\t1",
+		"b\t2",
+		"c\t3",
+	}
+	unsorted := []string{
+		"b\t2",
+		"a\t1",
+		"c\t3",
+	}
+
+	// Проверяем по 2 колонке
+	s := Sorter{Column: 2}
+
+	if !s.isSorted(sorted) {
+		t.Error("expected slice sorted by 2nd column to be detected as sorted")
+	}
+
+	if s.isSorted(unsorted) {
+		t.Error("expected slice unsorted by 2nd column to be detected as unsorted")
+	}
+}
+
+func TestCheckDisorder(t *testing.T) {
+	t.Setenv("LC_ALL", "C") // текст ошибки зависит от языка окружения
+	inputs := []io.Reader{
+		strings.NewReader("a\nb\n"),
+		strings.NewReader("c\n\na\n"),
+	}
+
+	s := Sorter{}
+	err := s.Check(context.Background(), inputs, []string{"first.txt", "second.txt"})
+	var disorder *DisorderError
+	if !errors.As(err, &disorder) {
+		t.Fatalf("expected DisorderError, got %v", err)
+	}
+	if want := "second.txt:3: disorder: a"; disorder.Error() != want {
+		t.Errorf("expected %q, got %q", want, disorder.Error())
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	lines := []string{"a\t1", "b\t1", "c\t2"}
+
+	s := Sorter{Keys: []KeySpec{{StartField: 2, EndField: 2}}}
+	if !s.isSorted(lines) {
+		t.Error("expected lines to be sorted")
+	}
+
+	s.Unique = true
+	if s.isSorted(lines) {
+		t.Error("expected equal adjacent keys to fail the check with -u")
+	}
+}
+
+func TestSortWithInvalidColumn(t *testing.T) {
+	input := []string{"a\tb", "c"}
+	s := Sorter{Column: 2}
+	err := s.SortLines(input)
+	if err == nil {
+		t.Error("expected error due to missing column")
+	}
+}
+
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    KeySpec
+		wantErr bool
+	}{
+		{"2", KeySpec{StartField: 2}, false},
+		{"2,2n", KeySpec{StartField: 2, EndField: 2, Numeric: true}, false},
+		{"1r,3", KeySpec{StartField: 1, EndField: 3, Reverse: true}, false},
+		{"3,3Mb", KeySpec{StartField: 3, EndField: 3, MonthCheck: true, SkipEndBlanks: true}, false},
+		{"2.3b,2.5", KeySpec{StartField: 2, StartChar: 3, EndField: 2, EndChar: 5, SkipBlanks: true}, false},
+		{"1.2,3.0n", KeySpec{StartField: 1, StartChar: 2, EndField: 3, Numeric: true}, false},
+		{"2.0", KeySpec{}, true},
+		{"2.", KeySpec{}, true},
+		{"2.x", KeySpec{}, true},
+		{"0", KeySpec{}, true},
+		{"2,1", KeySpec{}, true},
+		{"2x", KeySpec{}, true},
+		{"2nh", KeySpec{}, true},
+		{"price:n", KeySpec{StartName: "price", EndName: "price", Numeric: true}, false},
+		{"from,to:r", KeySpec{StartName: "from", EndName: "to", Reverse: true}, false},
+		{"price:x", KeySpec{}, true},
+		{",to", KeySpec{}, true},
+		{".user.id:n", KeySpec{Path: ".user.id", Numeric: true}, false},
+		{".items[0].size:hr", KeySpec{Path: ".items[0].size", HumanReadable: true, Reverse: true}, false},
+		{".a..b", KeySpec{}, true},
+		{".a[x]", KeySpec{}, true},
+		{"2,2D", KeySpec{StartField: 2, EndField: 2, Date: true}, false},
+		{"2Dn", KeySpec{}, true},
+		{"1,1Rf", KeySpec{StartField: 1, EndField: 1, Random: true, FoldCase: true}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeySpec(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeySpec(%q) error: %v, wantErr: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeySpec(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSortMultipleKeys(t *testing.T) {
+	want := []string{
+		"d\t1",
+		"b\t1",
+		"c\t10",
+		"a\t10",
+	}
+
+	for _, sortType := range []bool{true, false} {
+		input := []string{"a\t10", "b\t1", "c\t10", "d\t1"}
+		s := Sorter{
+			SortType: sortType,
+			Keys: []KeySpec{
+				{StartField: 2, EndField: 2, Numeric: true},
+				{StartField: 1, EndField: 1, Reverse: true},
+			},
+		}
+		if err := s.SortLines(input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(input, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, input)
+		}
+	}
+}
+
+func TestKeyTextFields(t *testing.T) {
+	line := "a\tb\tc"
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{1, 1, "a"},
+		{2, 2, "b"},
+		{2, 0, "b\tc"},
+		{1, 2, "a\tb"},
+		{3, 5, "c"},
+	}
+
+	for _, tt := range tests {
+		got, err := fieldSplitter{sep: "\t"}.keyText(line, &KeySpec{StartField: tt.start, EndField: tt.end})
+		if err != nil {
+			t.Fatalf("keyText(%d, %d) unexpected error: %v", tt.start, tt.end, err)
+		}
+		if got != tt.want {
+			t.Errorf("keyText(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestKeyTextChars(t *testing.T) {
+	tests := []struct {
+		name string
+		fs   fieldSplitter
+		line string
+		spec string
+		want string
+	}{
+		{"inside field", fieldSplitter{sep: "\t"}, "INV-20240117-004", "1.5,1.12", "20240117"},
+		{"cyrillic runes", fieldSplitter{sep: ";"}, "а;Счет-2024", "2.6,2.9", "2024"},
+		{"to end of field", fieldSplitter{sep: ";"}, "ab;cdef;g", "2.2,2", "def"},
+		{"to end of line", fieldSplitter{sep: ";"}, "ab;cdef;g", "2.3", "ef;g"},
+		{"across fields", fieldSplitter{sep: ";"}, "ab;cdef;gh", "1.2,3.1", "b;cdef;g"},
+		{"start past field", fieldSplitter{sep: ";"}, "ab;cd", "1.4,1", ""},
+		{"blanks counted", fieldSplitter{blanks: true}, "x   abc", "2.2,2.2", " "},
+		{"blanks skipped", fieldSplitter{blanks: true}, "x   abc", "2.2b,2.2b", "b"},
+		{"end blanks skipped", fieldSplitter{blanks: true}, "x   abc", "2,2.2b", "   ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseKeySpec(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseKeySpec(%q): %v", tt.spec, err)
+			}
+			got, err := tt.fs.keyText(tt.line, &k)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSortByCharPosition(t *testing.T) {
+	input := []string{"INV-20240117-004", "ACT-20231231-101", "INV-20240105-002"}
+	want := []string{"ACT-20231231-101", "INV-20240105-002", "INV-20240117-004"}
+
+	s, err := New(WithKeys("1.5,1.12n")) // дата внутри первого поля
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestSkipLeadingBlanks(t *testing.T) {
+	input := []string{"x  b", "y a", "z   c"}
+	s := Sorter{Keys: []KeySpec{{StartField: 2}}, BlankFields: true, SkipBlanks: true, SortType: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"y a", "x  b", "z   c"}; !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestFieldSeparators(t *testing.T) {
+	tests := []struct {
+		name  string
+		fs    fieldSplitter
+		line  string
+		field int
+		want  string
+	}{
+		{"colon", fieldSplitter{sep: ":"}, "root:x:0:0", 3, "0"},
+		{"multibyte", fieldSplitter{sep: "→"}, "a→бв→c", 2, "бв"},
+		{"word separator", fieldSplitter{sep: "::"}, "a::b:c::d", 2, "b:c"},
+		{"blanks first", fieldSplitter{blanks: true}, "  foo   bar", 1, "  foo"},
+		{"blanks second", fieldSplitter{blanks: true}, "  foo \t bar", 2, " \t bar"},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.fs.keyText(tt.line, &KeySpec{StartField: tt.field, EndField: tt.field})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := (fieldSplitter{blanks: true}).keyText("foo  ", &KeySpec{StartField: 3, EndField: 3}); err == nil {
+		t.Error("expected error due to missing column")
+	}
+}
+
+func TestSortBySeparator(t *testing.T) {
+	input := []string{"b,3", "a,1", "c,2"}
+	want := []string{"a,1", "c,2", "b,3"}
+
+	s := Sorter{Column: 2, Separator: ",", Numeric: true}
+	if err := s.SortLines(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(input, want) {
+		t.Errorf("expected %v, got %v", want, input)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	inputs := []io.Reader{
+		strings.NewReader("1\n4\n9\n"),
+		strings.NewReader("2\n3\n10\n"),
+		strings.NewReader(""),
+		strings.NewReader("4\n5"),
+	}
+	names := []string{"a", "b", "c", "d"}
+	want := "1\n2\n3\n4\n5\n9\n10\n"
+
+	s := Sorter{Numeric: true, Unique: true}
+	var out strings.Builder
+	if err := s.Merge(context.Background(), inputs, names, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestMergeCheckInputs(t *testing.T) {
+	inputs := []io.Reader{
+		strings.NewReader("a\nb\n"),
+		strings.NewReader("a\nc\nb\n"),
+	}
+
+	s := Sorter{CheckInputs: true}
+	err := s.Merge(context.Background(), inputs, []string{"first.txt", "second.txt"}, io.Discard)
+	if err == nil {
+		t.Fatal("expected error for unsorted input")
+	}
+	if !strings.Contains(err.Error(), "second.txt:3") {
+		t.Errorf("expected error to point to second.txt:3, got %v", err)
+	}
+}
+
+func TestParallelSortMatchesSequential(t *testing.T) {
+	input := make([]string, 50000)
+	for i := range input {
+		input[i] = fmt.Sprintf("%d\t%d", (i*7919)%1000, i%13)
+	}
+
+	for _, stable := range []bool{false, true} {
+		want := slices.Clone(input)
+		seq := Sorter{Column: 1, Numeric: true, Stable: stable, SortType: true}
+		if err := seq.SortLines(want); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, workers := range []int{2, 3, 8} {
+			got := slices.Clone(input)
+			par := Sorter{Column: 1, Numeric: true, Stable: stable, SortType: true, Parallel: workers}
+			if err := par.SortLines(got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("stable=%t, parallel=%d: result differs from sequential sort", stable, workers)
+			}
+		}
+	}
+}
+
+func TestParallelSortError(t *testing.T) {
+	input := make([]string, 20000)
+	for i := range input {
+		input[i] = fmt.Sprintf("%d", i)
+	}
+	input[15000] = "bad"
+	input[19000] = "worse"
+
+	s := Sorter{Numeric: true, SortType: true, Parallel: 4}
+	err := s.SortLines(input)
+	if err == nil || !strings.Contains(err.Error(), "'bad'") {
+		t.Errorf("expected error for the first bad line, got %v", err)
+	}
+}
+
+func TestSortExternal(t *testing.T) {
+	input := make([]string, 1000)
+	for i := range input {
+		input[i] = fmt.Sprintf("%d\tline", (i*7919)%1000)
+	}
+	want := slices.Clone(input)
+
+	s := Sorter{Numeric: true, Column: 1}
+	if err := s.SortLines(want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Буфер на пару десятков строк, чтобы получилось много кусков и несколько проходов слияния
+	ext := Sorter{Numeric: true, Column: 1, BufferSize: 20 * lineOverhead, TempDir: t.TempDir()}
+	var out strings.Builder
+	if err := ext.SortExternal(context.Background(), strings.NewReader(strings.Join(input, "\n")), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if !slices.Equal(got, want) {
+		t.Errorf("external sort differs from in-memory sort")
+	}
+	if entries, _ := os.ReadDir(ext.TempDir); len(entries) != 0 {
+		t.Errorf("expected temporary files to be removed, got %d left", len(entries))
+	}
+}
+
+func TestSortExternalUnique(t *testing.T) {
+	input := "b\na\nb\nc\na\n"
+	want := "a\nb\nc\n"
+
+	s := Sorter{Column: 1, Unique: true, BufferSize: 2 * lineOverhead, TempDir: t.TempDir()}
+	var out strings.Builder
+	if err := s.SortExternal(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	s, err := New(WithKeys("2,2n", "1,1r"), WithSeparator(","))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	if err := s.Sort(context.Background(), strings.NewReader("a,10\nb,2\nc,2\n"), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "c,2\nb,2\na,10\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		want error
+	}{
+		{"key", WithKeys("0"), ErrInvalidKey},
+		{"modifier", WithKeys("2x"), ErrInvalidKey},
+		{"collation", WithCollation("fr"), ErrInvalidOption},
+		{"buffer", WithBufferSize("-1K"), ErrInvalidOption},
+		{"parallel", WithParallel(-1), ErrInvalidOption},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opt)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSortErrorKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Sorter
+		lines []string
+		want  error
+	}{
+		{"numeric", Sorter{Column: 1, Numeric: true, SortType: true}, []string{"1", "x"}, ErrNotNumber},
+		{"field", Sorter{Column: 2, SortType: true}, []string{"a\t1", "b"}, ErrMissingField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.SortLines(tt.lines)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSortCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	input := strings.Repeat("b\na\n", ctxCheckEvery)
+	var out strings.Builder
+	if err := s.Sort(ctx, strings.NewReader(input), &out); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSortErrorLine(t *testing.T) {
+	s, err := New(WithNumeric(), WithLanguage(English))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	err = s.Sort(context.Background(), strings.NewReader("3\n\n1\nx\n"), &out)
+	var se *SortError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *SortError, got %v", err)
+	}
+	if se.Line != 4 || se.Column != 1 || se.Value != "x" || !errors.Is(err, ErrNotNumber) {
+		t.Errorf("unexpected error fields: %+v", se)
+	}
+	if want := "line 4: field 1: not a number: 'x'"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestSortErrorLanguage(t *testing.T) {
+	e := &SortError{File: "data.txt", Line: 2, Column: 3, Value: "a", Reason: ErrMissingField}
+
+	e.Lang = English
+	if want := "data.txt:2: field 3: no such field in line 'a'"; e.Error() != want {
+		t.Errorf("expected %q, got %q", want, e.Error())
+	}
+	e.Lang = Russian
+	if want := "data.txt:2: поле 3: в строке нет такого поля: 'a'"; e.Error() != want {
+		t.Errorf("expected %q, got %q", want, e.Error())
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "ru_RU.UTF-8")
+	if LanguageFromEnv() != Russian {
+		t.Errorf("expected Russian for LANG=ru_RU.UTF-8")
+	}
+	t.Setenv("LANG", "C")
+	if LanguageFromEnv() != English {
+		t.Errorf("expected English for LANG=C")
+	}
+}
+
+func TestOptionErrorLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	for lang, want := range map[string]string{
+		"C":           "invalid key '0': invalid start",
+		"ru_RU.UTF-8": "некорректный ключ '0': неверное начало",
+	} {
+		t.Setenv("LANG", lang)
+		_, err := ParseKeySpec("0")
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("LANG=%s: expected ErrInvalidKey, got %v", lang, err)
+		}
+		if err.Error() != want {
+			t.Errorf("LANG=%s: expected %q, got %q", lang, want, err.Error())
+		}
+	}
+
+	t.Setenv("LANG", "ru_RU.UTF-8")
+	if want := "строка имеет меньше столбцов"; ErrMissingField.Error() != want {
+		t.Errorf("expected %q, got %q", want, ErrMissingField.Error())
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	lines := []string{"2", "a", "1", "b"}
+	many := make([]string, 4*minParallelChunk)
+	for i := range many {
+		many[i] = strconv.Itoa(i)
+	}
+	many[10], many[len(many)-1] = "a", "b"
+
+	tests := []struct {
+		name  string
+		s     Sorter
+		lines []string
+		want  []int
+	}{
+		{"SortA", Sorter{Numeric: true, SortType: true}, lines, []int{2, 4}},
+		{"SortB", Sorter{Numeric: true}, lines, []int{2, 4}},
+		{"parallel", Sorter{Numeric: true, SortType: true, Parallel: 4}, many, []int{11, len(many)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.CollectErrors = true
+			err := tt.s.SortLines(slices.Clone(tt.lines))
+			var errs SortErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected SortErrors, got %v", err)
+			}
+			var got []int
+			for _, e := range errs {
+				got = append(got, e.Line)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSortExternalErrorLines(t *testing.T) {
+	s := Sorter{Numeric: true, SortType: true, CollectErrors: true, BufferSize: 2 * lineOverhead, TempDir: t.TempDir()}
+	var out strings.Builder
+	err := s.SortExternal(context.Background(), strings.NewReader("1\n2\n\nx\n3\ny\n"), &out)
+
+	var errs SortErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected two errors, got %v", err)
+	}
+	if errs[0].Line != 4 || errs[1].Line != 6 {
+		t.Errorf("expected lines 4 and 6, got %d and %d", errs[0].Line, errs[1].Line)
+	}
+}
+
+func TestCheckErrorLine(t *testing.T) {
+	s := Sorter{Numeric: true}
+	inputs := []io.Reader{strings.NewReader("1\n2\n"), strings.NewReader("3\nx\n")}
+	err := s.Check(context.Background(), inputs, []string{"a", "b"})
+
+	var se *SortError
+	if !errors.As(err, &se) || se.File != "b" || se.Line != 2 {
+		t.Errorf("expected error at b:2, got %v", err)
+	}
+}
+
+func TestBadLinesLenient(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Sorter
+		input []string
+		want  []string
+	}{
+		{
+			name:  "numeric",
+			s:     Sorter{Numeric: true, SortType: true, BadLines: BadLineLenient},
+			input: []string{"3", "x", "-5", "1"},
+			want:  []string{"x", "-5", "1", "3"},
+		},
+		{
+			name:  "reverse",
+			s:     Sorter{Numeric: true, Reverse: true, BadLines: BadLineLenient},
+			input: []string{"3", "x", "-5", "1"},
+			want:  []string{"3", "1", "-5", "x"},
+		},
+		{
+			name:  "month",
+			s:     Sorter{MonthCheck: true, SortType: true, BadLines: BadLineLenient},
+			input: []string{"Feb", "abc", "Jan"},
+			want:  []string{"abc", "Jan", "Feb"},
+		},
+		{
+			name:  "missing field",
+			s:     Sorter{Keys: []KeySpec{{StartField: 2, EndField: 2}}, SortType: true, BadLines: BadLineLenient},
+			input: []string{"a\tb", "c", "d\ta"},
+			want:  []string{"c", "d\ta", "a\tb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := slices.Clone(tt.input)
+			if err := tt.s.SortLines(lines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(lines, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, lines)
+			}
+		})
+	}
+}
+
+func TestBadLinesQuarantine(t *testing.T) {
+	input := "3\nx\n1\n\n2\nJan\n"
+	tests := []struct {
+		name string
+		s    Sorter
+	}{
+		{"memory", Sorter{Numeric: true, SortType: true}},
+		{"external", Sorter{Numeric: true, SortType: true, BufferSize: 2 * lineOverhead, TempDir: t.TempDir()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, reject strings.Builder
+			if err := WithQuarantine(&reject)(&tt.s); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.s.Sort(context.Background(), strings.NewReader(input), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := "1\n2\n3\n"; out.String() != want {
+				t.Errorf("expected %q, got %q", want, out.String())
+			}
+			if want := "x\nJan\n"; reject.String() != want {
+				t.Errorf("expected reject %q, got %q", want, reject.String())
+			}
+		})
+	}
+}
+
+func TestMergeQuarantine(t *testing.T) {
+	var out, reject strings.Builder
+	s := Sorter{Numeric: true, BadLines: BadLineQuarantine, Reject: &reject}
+	inputs := []io.Reader{strings.NewReader("1\nx\n4\n"), strings.NewReader("2\n3\n")}
+	if err := s.Merge(context.Background(), inputs, []string{"a", "b"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "1\n2\n3\n4\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+	if reject.String() != "x\n" {
+		t.Errorf("expected reject %q, got %q", "x\n", reject.String())
+	}
+}
+
+func TestParseBadLinePolicy(t *testing.T) {
+	for _, p := range []BadLinePolicy{BadLineStrict, BadLineLenient, BadLineQuarantine} {
+		got, err := ParseBadLinePolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseBadLinePolicy(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParseBadLinePolicy("skip"); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+}
+
+func TestSortRecordSeparators(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Sorter
+		input string
+		want  string
+	}{
+		{"nul", Sorter{RecordSep: "\x00"}, "b\x00a\nx\x00c", "a\nx\x00b\x00c\x00"},
+		{"multi-byte", Sorter{RecordSep: "||"}, "b||a|c||", "a|c||b||"},
+		{"nul external", Sorter{RecordSep: "\x00", BufferSize: 2 * lineOverhead}, "d\x00b\x00c\x00a", "a\x00b\x00c\x00d\x00"},
+		{"crlf", Sorter{}, "b\r\nc\r\na\r\n", "a\r\nb\r\nc\r\n"},
+		{"lf", Sorter{}, "b\nc\r\na\n", "a\nb\nc\n"},
+		{"keep blank", Sorter{KeepBlank: true}, "b\n\na\n", "\na\nb\n"},
+		{"keep blank numeric", Sorter{KeepBlank: true, Numeric: true, SortType: true}, "2\n \n1\n", " \n1\n2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.TempDir = t.TempDir()
+			var out strings.Builder
+			if err := tt.s.Sort(context.Background(), strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestSortLongRecord(t *testing.T) {
+	long := strings.Repeat("x", 1<<20) // больше любого буфера чтения
+	var out strings.Builder
+	s := Sorter{SortType: true}
+	if err := s.Sort(context.Background(), strings.NewReader(long+"\na\n"), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a\n" + long + "\n"; out.String() != want {
+		t.Errorf("long record lost, got %d bytes", out.Len())
+	}
+}
+
+func TestMergeCRLF(t *testing.T) {
+	var out strings.Builder
+	s := Sorter{}
+	inputs := []io.Reader{strings.NewReader("a\r\nc\r\n"), strings.NewReader("b\r\n")}
+	if err := s.Merge(context.Background(), inputs, []string{"x", "y"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a\r\nb\r\nc\r\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		enc     Encoding
+		input   []byte
+		want    string
+		wantEnc Encoding
+		wantBOM bool
+	}{
+		{"cp1251", EncodingCP1251, []byte{0xCF, 0xF0, 0xE8, 0xE2, 0xE5, 0xF2, '\n'}, "Привет\n", EncodingCP1251, false},
+		{"koi8-r", EncodingKOI8R, []byte{0xF0, 0xD2, 0xC9, 0xD7, 0xC5, 0xD4, '\n'}, "Привет\n", EncodingKOI8R, false},
+		{"auto utf-8", EncodingAuto, []byte("ё\n"), "ё\n", EncodingUTF8, false},
+		{"auto utf-8 bom", EncodingAuto, []byte("\xEF\xBB\xBFa\n"), "a\n", EncodingUTF8, true},
+		{"auto utf-16le", EncodingAuto, []byte{0xFF, 0xFE, 'a', 0, 0x16, 0x04, '\n', 0}, "aЖ\n", EncodingUTF16LE, true},
+		{"auto utf-16be", EncodingAuto, []byte{0xFE, 0xFF, 0, 'a', 0x04, 0x16, 0, '\n'}, "aЖ\n", EncodingUTF16BE, true},
+		{"utf-16 bom wins", EncodingUTF16LE, []byte{0xFE, 0xFF, 0, 'a'}, "a", EncodingUTF16BE, true},
+		{"surrogate pair", EncodingUTF16LE, []byte{0x3D, 0xD8, 0x00, 0xDE}, "😀", EncodingUTF16LE, false},
+		{"odd byte", EncodingUTF16BE, []byte{0, 'a', 0}, "a\uFFFD", EncodingUTF16BE, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Читаем по одному байту, чтобы символы резались между Read
+			d, err := NewDecoder(iotest.OneByteReader(bytes.NewReader(tt.input)), tt.enc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if d.Encoding() != tt.wantEnc || d.BOM() != tt.wantBOM {
+				t.Errorf("expected %v bom=%v, got %v bom=%v", tt.wantEnc, tt.wantBOM, d.Encoding(), d.BOM())
+			}
+		})
+	}
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	text := "Ёжик a\nщука 😀\n"
+	for _, enc := range []Encoding{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingCP1251, EncodingKOI8R} {
+		t.Run(enc.String(), func(t *testing.T) {
+			want := text
+			if enc.table() != nil {
+				want = strings.ReplaceAll(text, "😀", "?") // в однобайтовой кодировке его нет
+			}
+
+			var buf bytes.Buffer
+			e := NewEncoder(&buf, enc, true)
+			for _, b := range []byte(text) { // символы UTF-8 режутся между Write
+				if _, err := e.Write([]byte{b}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			detect := EncodingAuto // UTF-8 и UTF-16 определяются по BOM
+			if enc.table() != nil {
+				detect = enc
+			}
+			d, err := NewDecoder(&buf, detect)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+			if d.Encoding() != enc || d.BOM() != (enc.bom() != nil) {
+				t.Errorf("expected %v detected from BOM, got %v bom=%v", enc, d.Encoding(), d.BOM())
+			}
+		})
+	}
+}
+
+func TestSortCP1251(t *testing.T) {
+	// "яблоко\nарбуз\n" в cp1251
+	input := []byte{0xFF, 0xE1, 0xEB, 0xEE, 0xEA, 0xEE, '\n', 0xE0, 0xF0, 0xE1, 0xF3, 0xE7, '\n'}
+	d, err := NewDecoder(bytes.NewReader(input), EncodingCP1251)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := Sorter{}
+	if err := s.Sort(context.Background(), d, NewEncoder(&out, EncodingCP1251, false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []byte{0xE0, 0xF0, 0xE1, 0xF3, 0xE7, '\n', 0xFF, 0xE1, 0xEB, 0xEE, 0xEA, 0xEE, '\n'}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("expected %q, got %q", want, out.Bytes())
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for name, want := range map[string]Encoding{
+		"auto": EncodingAuto, "UTF-8": EncodingUTF8, "utf-16": EncodingUTF16LE, "utf-16be": EncodingUTF16BE,
+		"windows-1251": EncodingCP1251, "cp1251": EncodingCP1251, "KOI8-R": EncodingKOI8R,
+	} {
+		if got, err := ParseEncoding(name); err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseEncoding("latin1"); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+}
+
+func TestCSVKeyText(t *testing.T) {
+	line := `"Smith, J",10,"say ""hi""",,"a` + "\n" + `b"`
+	fs := fieldSplitter{sep: ",", csv: true}
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{1, 1, "Smith, J"},
+		{2, 2, "10"},
+		{3, 3, `say "hi"`},
+		{4, 4, ""},
+		{5, 5, "a\nb"},
+		{1, 2, "Smith, J,10"},
+	}
+
+	for _, tt := range tests {
+		got, err := fs.keyText(line, &KeySpec{StartField: tt.start, EndField: tt.end})
+		if err != nil {
+			t.Fatalf("keyText(%d, %d) unexpected error: %v", tt.start, tt.end, err)
+		}
+		if got != tt.want {
+			t.Errorf("keyText(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+	if want := []string{"Smith, J", "10", `say "hi"`, "", "a\nb"}; !slices.Equal(fs.fields(line), want) {
+		t.Errorf("expected fields %q, got %q", want, fs.fields(line))
+	}
+}
+
+func TestSortCSV(t *testing.T) {
+	input := "name,price\n\"Smith, J\",10\nAdams,\"2\"\n\"Doe\n\"\"JD\"\"\",5\n"
+	tests := []struct {
+		name string
+		s    Sorter
+		want string
+	}{
+		{"by name", Sorter{CSV: true, Header: 1, Keys: []KeySpec{{StartName: "price", EndName: "price", Numeric: true}}},
+			"name,price\nAdams,2\n\"Doe\n\"\"JD\"\"\",5\n\"Smith, J\",10\n"},
+		{"by position", Sorter{CSV: true, Header: 1, Keys: []KeySpec{{StartField: 1, EndField: 1, Reverse: true}}},
+			"name,price\n\"Smith, J\",10\n\"Doe\n\"\"JD\"\"\",5\nAdams,2\n"},
+		{"external", Sorter{CSV: true, Header: 1, BufferSize: 2 * lineOverhead, Keys: []KeySpec{{StartName: "price", EndName: "price", Numeric: true}}},
+			"name,price\nAdams,2\n\"Doe\n\"\"JD\"\"\",5\n\"Smith, J\",10\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.TempDir = t.TempDir()
+			tt.s.SortType = true
+			var out strings.Builder
+			if err := tt.s.Sort(context.Background(), strings.NewReader(input), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
+
+// Кавычка в середине поля без кавычек его не открывает и не склеивает следующие строки
+func TestSortCSVBareQuote(t *testing.T) {
+	s := Sorter{CSV: true, SortType: true, Keys: []KeySpec{{StartField: 2, EndField: 2, Numeric: true}}}
+	var out strings.Builder
+	if err := s.Sort(context.Background(), strings.NewReader("c,3\n5\" disk,2\na,1\nb,0\n"), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "b,0\na,1\n\"5\"\" disk\",2\nc,3\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestSortHeaderErrors(t *testing.T) {
+	s := Sorter{CSV: true, Header: 1, Numeric: true}
+	var out strings.Builder
+	err := s.Sort(context.Background(), strings.NewReader("n\n2\nx\n"), &out)
+	var se *SortError
+	if !errors.As(err, &se) || se.Line != 3 {
+		t.Errorf("expected SortError on line 3 counting the header, got %v", err)
+	}
+
+	s = Sorter{CSV: true, Header: 1, Keys: []KeySpec{{StartName: "missing", EndName: "missing"}}}
+	if err := s.Sort(context.Background(), strings.NewReader("n\n1\n"), &out); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for unknown column, got %v", err)
+	}
+	s = Sorter{Keys: []KeySpec{{StartName: "n", EndName: "n"}}}
+	if err := s.Sort(context.Background(), strings.NewReader("n\n1\n"), &out); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey without header, got %v", err)
+	}
+}
+
+// Вызовы не меняют Sorter, поэтому один Sorter сортирует входы с разными
+// заголовками из нескольких горутин, и ошибка одного вызова не видна другим
+func TestSorterShared(t *testing.T) {
+	s := Sorter{CSV: true, Header: 1, Numeric: true, Keys: []KeySpec{{StartName: "n", EndName: "n", Numeric: true}}}
+	tests := []struct {
+		input, want string
+		fail        bool
+	}{
+		{"n,v\n3,c\n1,a\n2,b\n", "n,v\n1,a\n2,b\n3,c\n", false},
+		{"v,n\nc,3\na,1\nb,2\n", "v,n\na,1\nb,2\nc,3\n", false},
+		{"n,v\nx,c\n1,a\n", "", true},
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		for _, tt := range tests {
+			wg.Go(func() {
+				var out strings.Builder
+				err := s.Sort(context.Background(), strings.NewReader(tt.input), &out)
+				if tt.fail {
+					if !errors.Is(err, ErrNotNumber) {
+						t.Errorf("expected ErrNotNumber for %q, got %v", tt.input, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("unexpected error for %q: %v", tt.input, err)
+				} else if out.String() != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, out.String())
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	if k := s.Keys[0]; k.StartField != 0 || k.EndField != 0 {
+		t.Errorf("expected the key to stay unresolved, got fields %d-%d", k.StartField, k.EndField)
+	}
+}
+
+func TestMergeCSVHeader(t *testing.T) {
+	var out strings.Builder
+	s := Sorter{CSV: true, Header: 1, Keys: []KeySpec{{StartName: "id", EndName: "id", Numeric: true}}}
+	inputs := []io.Reader{strings.NewReader("id,v\n1,a\n3,\"c,d\"\n"), strings.NewReader("id,v\n2,b\n")}
+	if err := s.Merge(context.Background(), inputs, []string{"x", "y"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "id,v\n1,a\n2,b\n3,\"c,d\"\n"; out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+
+	inputs = []io.Reader{strings.NewReader("id,v\n1,a\n2,b\n")}
+	if err := s.Check(context.Background(), inputs, []string{"x"}); err != nil {
+		t.Errorf("expected sorted input with header, got %v", err)
+	}
+}
+
+func TestJSONKeyText(t *testing.T) {
+	line := `{"user": {"id": 42, "name": "Анна \"A\""}, "tags": ["x", {"n": 1.5}], "ok": true, "none": null}`
+	tests := []struct {
+		path      string
+		want      string
+		wantFound bool
+	}{
+		{".user.id", "42", true},
+		{".user.name", `Анна "A"`, true},
+		{".tags[1].n", "1.5", true},
+		{".tags[0]", "x", true},
+		{".ok", "true", true},
+		{".user", `{"id": 42, "name": "Анна \"A\""}`, true},
+		{".none", "", false},
+		{".missing", "", false},
+		{".tags[5]", "", false},
+		{".user.id.deeper", "", false},
+	}
+
+	for _, tt := range tests {
+		got, found, err := jsonKeyText(line, tt.path)
+		if err != nil {
+			t.Fatalf("jsonKeyText(%q) unexpected error: %v", tt.path, err)
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("jsonKeyText(%q) = %q, %t, want %q, %t", tt.path, got, found, tt.want, tt.wantFound)
+		}
+	}
+	if _, _, err := jsonKeyText("{broken", ".a"); !errors.Is(err, ErrNotJSON) {
+		t.Errorf("expected ErrNotJSON, got %v", err)
+	}
+}
+
+func TestSortJSON(t *testing.T) {
+	input := []string{
+		`{"ts": 3, "size": "2K"}`,
+		`{"ts":1,"size":"1M"}`,
+		`{"size": "10"}`,
+		`{"ts": null, "size": "1K"}`,
+		`{"ts": 2}`,
+	}
+	tests := []struct {
+		name string
+		s    Sorter
+		want []string
+	}{
+		{"numeric, missing first", Sorter{JSON: true, Keys: []KeySpec{{Path: ".ts", Numeric: true}}},
+			[]string{input[2], input[3], input[1], input[4], input[0]}},
+		{"reverse, missing last", Sorter{JSON: true, Keys: []KeySpec{{Path: ".ts", Numeric: true, Reverse: true}}},
+			[]string{input[0], input[4], input[1], input[2], input[3]}},
+		{"human", Sorter{JSON: true, Stable: true, Keys: []KeySpec{{Path: ".size", HumanReadable: true}}},
+			[]string{input[4], input[2], input[3], input[0], input[1]}},
+	}
+
+	for _, tt := range tests {
+		for _, sortType := range []bool{true, false} {
+			lines := slices.Clone(input)
+			tt.s.SortType = sortType
+			if err := tt.s.SortLines(lines); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if !slices.Equal(lines, tt.want) { // строки остаются байт в байт как во входе
+				t.Errorf("%s, SortType=%t: expected %q, got %q", tt.name, sortType, tt.want, lines)
+			}
+		}
+	}
+}
+
+func TestSortJSONBadLines(t *testing.T) {
+	s := Sorter{JSON: true, SortType: true, Language: English, Keys: []KeySpec{{Path: ".n", Numeric: true}}}
+	err := s.SortLines([]string{`{"n": 1}`, `n=2`})
+	var se *SortError
+	if !errors.As(err, &se) || !errors.Is(err, ErrNotJSON) || se.Line != 2 || se.Path != ".n" {
+		t.Fatalf("expected ErrNotJSON on line 2, got %v", err)
+	}
+	if want := "line 2: path .n: not valid JSON: 'n=2'"; se.Error() != want {
+		t.Errorf("expected %q, got %q", want, se.Error())
+	}
+
+	lines := []string{`{"n": 1}`, `n=2`}
+	s.BadLines = BadLineLenient
+	if err := s.SortLines(lines); err != nil || lines[0] != `n=2` {
+		t.Errorf("expected lenient bad line first, got %q, %v", lines, err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		text    string
+		layouts []string
+		want    time.Time
+	}{
+		{"2024-01-17T10:03:01+03:00", nil, time.Date(2024, 1, 17, 7, 3, 1, 0, time.UTC)},
+		{"2024-01-17T10:03:01.5Z", nil, time.Date(2024, 1, 17, 10, 3, 1, 5e8, time.UTC)},
+		{"17/Jan/2024:10:03:01 +0300", nil, time.Date(2024, 1, 17, 7, 3, 1, 0, time.UTC)},
+		{"2024-01-17 10:03:01", nil, time.Date(2024, 1, 17, 10, 3, 1, 0, moscow)}, // без пояса — TimeZone
+		{"17.01.2024", nil, time.Date(2024, 1, 17, 0, 0, 0, 0, moscow)},
+		{" 1705485781 ", nil, time.Unix(1705485781, 0)},
+		{"1705485781.25", []string{LayoutUnix}, time.Unix(1705485781, 25e7)},
+		{"1705485781250", []string{LayoutUnixMilli}, time.UnixMilli(1705485781250)},
+		{"17.01.2024 10:03", []string{"02.01.2006 15:04"}, time.Date(2024, 1, 17, 10, 3, 0, 0, moscow)},
+	}
+
+	for _, tt := range tests {
+		s := Sorter{DateLayouts: tt.layouts, TimeZone: moscow}
+		got, err := s.parseDate(tt.text)
+		if err != nil {
+			t.Errorf("parseDate(%q) unexpected error: %v", tt.text, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDate(%q) = %v, want %v", tt.text, got.UTC(), tt.want.UTC())
+		}
+	}
+
+	s := Sorter{DateLayouts: []string{time.DateOnly}}
+	if _, err := s.parseDate("17.01.2024"); err == nil {
+		t.Error("expected error for date not matching the layout")
+	}
+}
+
+func TestSortDate(t *testing.T) {
+	input := []string{
+		"b\t17/Jan/2024:10:03:01 +0300",
+		"a\t2024-01-17T08:00:00Z",
+		"c\t2024-01-16",
+	}
+	want := []string{input[2], input[0], input[1]}
+	for _, sortType := range []bool{true, false} {
+		lines := slices.Clone(input)
+		s := Sorter{SortType: sortType, Keys: []KeySpec{{StartField: 2, EndField: 2, Date: true}}}
+		if err := s.SortLines(lines); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(lines, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, lines)
+		}
+	}
+
+	// Даты за пределами 1678–2262 годов, где наносекунды с начала эпохи не помещаются в int64
+	for _, sortType := range []bool{true, false} {
+		lines := []string{"2024-01-01", "1600-01-01", "9999-12-31", "1600-01-01T00:00:00.5Z"}
+		s := Sorter{SortType: sortType, Date: true}
+		if err := s.SortLines(lines); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := []string{"1600-01-01", "1600-01-01T00:00:00.5Z", "2024-01-01", "9999-12-31"}; !slices.Equal(lines, want) {
+			t.Errorf("SortType=%t: expected %q, got %q", sortType, want, lines)
+		}
+	}
+
+	s := Sorter{SortType: true, Date: true}
+	err := s.SortLines([]string{"2024-01-17", "yesterday"})
+	var se *SortError
+	if !errors.As(err, &se) || !errors.Is(err, ErrNotDate) || se.Line != 2 {
+		t.Errorf("expected ErrNotDate on line 2, got %v", err)
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	for _, name := range []string{"UTC", "+03:00", "-0530"} {
+		if _, err := ParseTimeZone(name); err != nil {
+			t.Errorf("ParseTimeZone(%q) unexpected error: %v", name, err)
+		}
+	}
+	loc, _ := ParseTimeZone("+03:00")
+	if _, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != 3*60*60 {
+		t.Errorf("expected offset +03:00, got %d", offset)
+	}
+	if _, err := ParseTimeZone("Nowhere/City"); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption, got %v", err)
+	}
+	if layout, _ := ParseDateLayout("CLF"); layout != "02/Jan/2006:15:04:05 -0700" {
+		t.Errorf("expected clf preset, got %q", layout)
+	}
+}
+
+func TestRandomSort(t *testing.T) {
+	var input []string
+	for i := range 50 {
+		input = append(input, fmt.Sprintf("k%d\t%d", i%10, i))
+	}
+
+	sortWith := func(seed string, sortType bool) []string {
+		lines := slices.Clone(input)
+		s := Sorter{Random: true, Column: 1, Stable: true, SortType: sortType, RandomSeed: []byte(seed)}
+		if err := s.SortLines(lines); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return lines
+	}
+
+	got := sortWith("fixtures", true)
+	if !slices.Equal(got, sortWith("fixtures", true)) || !slices.Equal(got, sortWith("fixtures", false)) {
+		t.Error("expected the same order for the same seed")
+	}
+	if slices.Equal(got, sortWith("other", true)) {
+		t.Error("expected another order for another seed")
+	}
+
+	// Равные ключи идут подряд, внутри группы порядок ввода сохраняется при -s
+	seen := map[string]bool{}
+	for i, line := range got {
+		key, _, _ := strings.Cut(line, "\t")
+		prev, _, _ := strings.Cut(got[max(i-1, 0)], "\t")
+		if key != prev && seen[key] {
+			t.Fatalf("key %s is not grouped: %q", key, got)
+		}
+		seen[key] = true
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	input := "a\t1\nb\t2\na\t3\nc\t4\nb\t5\nd\t6\n"
+	shuffle := func(s Sorter) string {
+		var out strings.Builder
+		if err := s.Sort(context.Background(), strings.NewReader(input), &out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	got := shuffle(Sorter{Shuffle: true, RandomSeed: []byte("x")})
+	if got != shuffle(Sorter{Shuffle: true, RandomSeed: []byte("x")}) {
+		t.Error("expected the same shuffle for the same seed")
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	want := strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+	slices.Sort(lines)
+	slices.Sort(want)
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected a permutation of the input, got %q", got)
+	}
+
+	uniq := shuffle(Sorter{Shuffle: true, Unique: true, Column: 1, SortType: true, RandomSeed: []byte("x")})
+	lines = strings.Split(strings.TrimSuffix(uniq, "\n"), "\n")
+	slices.Sort(lines)
+	if want := []string{"a\t1", "b\t2", "c\t4", "d\t6"}; !slices.Equal(lines, want) {
+		t.Errorf("expected first line of each key, got %q", uniq)
+	}
+
+	s := Sorter{Shuffle: true, Numeric: true, Column: 2}
+	var out strings.Builder
+	if err := s.Sort(context.Background(), strings.NewReader("a\t1\nb\tx\n"), &out); !errors.Is(err, ErrNotNumber) {
+		t.Errorf("expected keys to be checked before shuffle, got %v", err)
+	}
+}
+
+// SortLines перемешивает строки так же, как Sort с тем же зерном, а не сортирует их
+func TestSortLinesShuffle(t *testing.T) {
+	var input strings.Builder
+	for i := range 50 {
+		fmt.Fprintf(&input, "%02d\n", i)
+	}
+	s := Sorter{Shuffle: true, RandomSeed: []byte("seed")}
+	var out strings.Builder
+	if err := s.Sort(context.Background(), strings.NewReader(input.String()), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(input.String(), "\n"), "\n")
+	if err := s.SortLines(lines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(lines, "\n") + "\n"; got != out.String() {
+		t.Errorf("expected the order of Sort %q, got %q", out.String(), got)
+	}
+	if slices.IsSorted(lines) {
+		t.Error("expected shuffled lines, got them sorted")
+	}
+
+	s = Sorter{Shuffle: true, Numeric: true, Column: 2}
+	if err := s.SortLines([]string{"a\t1", "b\tx"}); !errors.Is(err, ErrNotNumber) {
+		t.Errorf("expected keys to be checked before shuffle, got %v", err)
+	}
+}
+
+// Без RandomSeed каждый вызов выбирает свое зерно и не сохраняет его в Sorter
+func TestRandomSeedPerCall(t *testing.T) {
+	var input strings.Builder
+	for i := range 50 {
+		fmt.Fprintf(&input, "%d\n", i)
+	}
+	for _, s := range []*Sorter{{Random: true, Column: 1, SortType: true}, {Shuffle: true}} {
+		run := func() string {
+			var out strings.Builder
+			if err := s.Sort(context.Background(), strings.NewReader(input.String()), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return out.String()
+		}
+
+		first := run()
+		if s.RandomSeed != nil {
+			t.Errorf("expected RandomSeed to stay nil, got %x", s.RandomSeed)
+		}
+		if first == run() {
+			t.Error("expected a new order for each call without a seed")
+		}
+	}
+}
+
+func TestReadSeed(t *testing.T) {
+	dir := t.TempDir()
+	short := dir + "/short"
+	empty := dir + "/empty"
+	os.WriteFile(short, []byte("abc"), 0o644)
+	os.WriteFile(empty, nil, 0o644)
+
+	if seed, err := ReadSeed(short); err != nil || string(seed) != "abc" {
+		t.Errorf("expected short file to be the seed, got %q, %v", seed, err)
+	}
+	if _, err := ReadSeed(empty); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("expected ErrInvalidOption for empty source, got %v", err)
+	}
+}
+
+func BenchmarkSorterNumeric(b *testing.B) {
+	lines := make([]string, 1000000)
+	for i := 0; i < 1000000; i++ {
+		lines[i] = fmt.Sprintf("%d", 1000000-i)
+	}
+
+	s := Sorter{Numeric: true}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		_ = s.SortLines(lines)
+	}
+}
+
+func benchmarkSorterParallel(b *testing.B, workers int) {
+	lines := make([]string, 1000000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d", (i*7919)%1000003)
+	}
+	input := make([]string, len(lines))
+
+	s := Sorter{Numeric: true, SortType: true, Parallel: workers}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		copy(input, lines)
+		_ = s.SortLines(input)
+	}
+}
+
+func BenchmarkSorterSequential(b *testing.B) { benchmarkSorterParallel(b, 1) }
+
+func BenchmarkSorterParallel4(b *testing.B) { benchmarkSorterParallel(b, 4) }
+
+func BenchmarkSorterParallel8(b *testing.B) { benchmarkSorterParallel(b, 8) }
+
+func generateHumanReadableLines(n int) []string {
+	units := []string{"B", "K", "M", "G"}
+	lines := make([]string, n)
+	for i := 0; i < n; i++ {
+		lines[i] = fmt.Sprintf("%d%s", (n-i)*10, units[i%len(units)])
+	}
+	return lines
+}
+
+func BenchmarkSorterHumanReadable(b *testing.B) {
+	lines := generateHumanReadableLines(1000000)
+
+	s := Sorter{HumanReadable: true}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		_ = s.SortLines(lines)
+	}
+}
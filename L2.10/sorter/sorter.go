@@ -0,0 +1,376 @@
+// Package sorter сортирует строки по ключам и флагам в духе GNU sort:
+// в памяти, внешней сортировкой через временные файлы и слиянием уже отсортированных входов
+package sorter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sorter сортирует строки по заданным флагам. Каждый вызов работает с копией
+// настроек, поэтому Sorter не меняется и его можно использовать из нескольких
+// горутин, пока поля не меняются. Reject при этом пишется из всех вызовов
+type Sorter struct {
+	Column          int
+	Keys            []KeySpec // ключи -k, если заданы, то Column не используется
+	Separator       string    // разделитель полей, по умолчанию табуляция
+	BlankFields     bool      // поля разделены сериями пробелов и табуляций, как в GNU sort
+	Numeric         bool
+	Reverse         bool
+	RemoveTBlanks   bool // убирать хвостовые пробелы ключа
+	SkipBlanks      bool // пропускать пробелы в начале полей ключа, как -b в GNU sort
+	Unique          bool
+	UniqueKeep      UniquePolicy // какая строка остается из группы дубликатов
+	UniqueBy        []KeySpec    // ключи для выбора строки при KeepBest
+	CountDuplicates bool         // печатать перед строкой число дубликатов, как uniq -c
+	CheckSort       bool
+	CheckInputs     bool // при слиянии (-m) проверять, что каждый вход отсортирован
+	HumanReadable   bool
+	MonthCheck      bool
+	MonthTables     []MonthTable // названия месяцев для -M, пусто — английские и русские
+	VersionSort     bool         // сравнение как версий: 1.9 < 1.10 (-V)
+	Date            bool         // сравнение как дат (--date)
+	Random          bool         // случайный порядок по ключевому хешу ключа, равные ключи рядом (-R)
+	Shuffle         bool         // перемешать строки целиком, без сортировки (--shuffle)
+	RandomSeed      []byte       // зерно для Random и Shuffle, nil — случайное на каждый запуск
+	GeneralNumeric  bool         // сравнение как чисел с плавающей точкой (-g)
+	DecimalComma    bool         // дробная часть отделяется запятой: 3,14
+	ThousandsSep    string       // разделитель разрядов, который выкидывается перед разбором: 1 000 000
+	HumanSI         bool         // для -h суффиксы K, M, G без i считаются степенями 1000, а не 1024
+	SortType        bool
+	Stable          bool           // сохранять порядок строк с равными ключами, без сравнения строк целиком (-s)
+	Parallel        int            // сколько потоков использует SortA, 0 и 1 — без параллелизма
+	Collation       Collation      // правила сравнения строк, по умолчанию побайтово
+	FoldCase        bool           // без учета регистра (-f)
+	Dictionary      bool           // сравнивать только буквы, цифры и пробелы (-d)
+	IgnoreNonPrint  bool           // игнорировать непечатные символы (-i)
+	BufferSize      int            // лимит памяти в байтах, 0 — сортировать целиком в памяти
+	TempDir         string         // каталог для временных файлов внешней сортировки
+	CollectErrors   bool           // собирать все строки с неразобранными ключами, а не останавливаться на первой
+	BadLines        BadLinePolicy  // что делать со строками, ключ которых не разобрался
+	Reject          io.Writer      // куда пишутся строки при BadLineQuarantine, nil — отбрасывать
+	RecordSep       string         // разделитель записей, по умолчанию перевод строки, "\x00" — как -z
+	KeepBlank       bool           // не выбрасывать пустые строки, они идут раньше остальных
+	CSV             bool           // записи в формате CSV (RFC 4180), Separator по умолчанию запятая
+	Header          int            // сколько первых записей входа — заголовок: он не сортируется и выводится первым
+	JSON            bool           // записи — JSON Lines: ключ по умолчанию — весь документ, строки не JSON — плохие
+	DateLayouts     []string       // раскладки дат Go, пробуются по порядку, пусто — RFC 3339, ISO, CLF, 02.01.2006 и Unix
+	TimeZone        *time.Location // пояс для дат без пояса, nil — UTC
+	Language        Language       // язык сообщений об ошибках, пустой — из LC_ALL, LC_MESSAGES или LANG
+
+	// Состояние вызова, заполняется только в копии из call
+	seed []byte // зерно вызова: RandomSeed или случайное
+	err  error  // первая ошибка ключа, найденная при сравнении
+}
+
+// Хранит строку и значения всех ее ключей
+type sortableLine struct {
+	line string
+	keys []sortKey
+}
+
+// Преобразовывает строки в sortableLine, находя ключи. Номера строк в ошибках — с 1 по порядку lines.
+// При CollectErrors проходит все строки и возвращает SortErrors, иначе первую ошибку
+func (s Sorter) buildSortableLines(lines []string) ([]sortableLine, error) {
+	specs := s.keys()
+	res := make([]sortableLine, len(lines))
+	keys := make([]sortKey, len(lines)*len(specs)) // одним куском, чтобы не аллоцировать на каждую строку
+
+	var errs SortErrors
+	for i, line := range lines {
+		lineKeys := keys[i*len(specs) : (i+1)*len(specs)]
+		if err := s.buildLineKeys(specs, line, i+1, lineKeys); err != nil {
+			if !s.CollectErrors {
+				return nil, err
+			}
+			errs = append(errs, err)
+		}
+
+		res[i] = sortableLine{
+			line: line,
+			keys: lineKeys,
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return res, nil
+}
+
+// Проверяет ключи всех строк заранее, чтобы сравнение не упало посреди сортировки.
+// Ошибки те же, что у buildSortableLines
+func (s *Sorter) checkKeys(lines []string) error {
+	var errs SortErrors
+	for i, line := range lines {
+		if err := s.checkLineKeys(line, i+1); err != nil {
+			if !s.CollectErrors {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SortLines сортирует строки на месте выбранным методом. Срез не может
+// уменьшиться, поэтому при BadLineQuarantine плохие строки отсеиваются только
+// при чтении в Sort и Merge, а здесь приводят к ошибке, как при BadLineStrict.
+// При Shuffle строки перемешиваются, дубликаты по той же причине не схлопываются
+func (s *Sorter) SortLines(lines []string) error {
+	c := s.call()
+	if c.Shuffle {
+		if err := c.checkKeys(lines); err != nil {
+			return err
+		}
+		c.shuffleLines(lines)
+		return nil
+	}
+	return c.sortLines(lines)
+}
+
+// Копия настроек для одного вызова: зерно, столбцы ключей с именами и ошибка
+// сравнения пишутся в нее, а не в s
+func (s *Sorter) call() *Sorter {
+	c := *s
+	c.Keys = slices.Clone(s.Keys)
+	c.UniqueBy = slices.Clone(s.UniqueBy)
+	c.err = nil
+	c.resetSeed()
+	return &c
+}
+
+// Сортирует строки выбранным методом с уже выбранным зерном
+func (s *Sorter) sortLines(lines []string) error {
+	if s.SortType {
+		return s.sortA(lines)
+	}
+	return s.sortB(lines)
+}
+
+// Sort читает строки из r, сортирует их и пишет результат в w, применяя Unique.
+// Первые Header записей выводятся первыми без сортировки, по ним находятся столбцы ключей с именами.
+// При BufferSize > 0 вход сортируется внешней сортировкой и целиком в память не читается,
+// кроме Shuffle: для перемешивания нужен весь вход
+func (s *Sorter) Sort(ctx context.Context, r io.Reader, w io.Writer) error {
+	return s.SortInputs(ctx, []io.Reader{r}, []string{""}, w)
+}
+
+// SortInputs сортирует входы, прочитанные подряд, как Sort один вход. Заголовок
+// из Header записей пропускается в каждом входе, выводится заголовок первого, как в Merge.
+// В ошибках ключей File — имя входа из names, Line — номер строки в нем
+func (s *Sorter) SortInputs(ctx context.Context, inputs []io.Reader, names []string, w io.Writer) error {
+	return s.call().sortInputs(ctx, inputs, names, w)
+}
+
+// Сортирует входы в рамках одного вызова
+func (s *Sorter) sortInputs(ctx context.Context, inputs []io.Reader, names []string, w io.Writer) error {
+	in, err := s.newInputRecords(inputs, names)
+	if err != nil {
+		return err
+	}
+	if s.BufferSize > 0 && !s.Shuffle { // Вход может не влезть в память
+		return s.sortExternal(ctx, in, w)
+	}
+
+	header := in.header
+	if err := s.resolveKeyNames(header); err != nil {
+		return err
+	}
+	lines, nums, err := s.readLines(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	if s.Shuffle {
+		lines, err = s.shuffle(lines)
+	} else {
+		err = s.sortLines(lines)
+	}
+	if err != nil {
+		return nums.renumber(err)
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := s.writeHeader(bw, in.eol(), header); err != nil {
+		return err
+	}
+	sink := s.newLineSink(bw, in.eol())
+	for i, line := range lines {
+		if err := canceled(ctx, i+1); err != nil {
+			return err
+		}
+		if err := sink.WriteLine(line); err != nil {
+			return err
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// SortA делает сортировку полученных строк, с помощью buildSortableLines
+func (s *Sorter) SortA(lines []string) error {
+	return s.call().sortA(lines)
+}
+
+// Сортирует методом SortA с уже выбранным зерном
+func (s *Sorter) sortA(lines []string) error {
+	s.err = nil
+
+	w := s.workers(len(lines))
+
+	var SLines []sortableLine
+	var err error
+	if w > 1 {
+		SLines, err = s.buildSortableLinesParallel(lines, w)
+	} else {
+		SLines, err = s.buildSortableLines(lines)
+	}
+	if err != nil {
+		s.err = err
+		return err
+	}
+
+	specs := s.keys()
+	cmp := func(a, b sortableLine) int {
+		if c := compareLinesA(specs, a, b); c != 0 {
+			return c
+		}
+		return s.lastResort(a.line, b.line)
+	}
+	if w > 1 {
+		parallelSort(SLines, w, s.stable(), cmp)
+	} else {
+		sortSlice(SLines, s.stable(), cmp)
+	}
+
+	for i := range lines {
+		lines[i] = SLines[i].line
+	}
+
+	return s.err
+}
+
+// SortB делает сортировку полученных строк, доставая ключи при каждом сравнении
+func (s *Sorter) SortB(lines []string) error {
+	return s.call().sortB(lines)
+}
+
+// Сортирует методом SortB с уже выбранным зерном
+func (s *Sorter) sortB(lines []string) error {
+	s.err = nil
+	if err := s.checkKeys(lines); err != nil {
+		s.err = err
+		return err
+	}
+	sortSlice(lines, s.stable(), s.compareLinesB)
+	return s.err
+}
+
+// Нужно ли сохранять порядок ввода для равных ключей. При -u тоже: иначе
+// из группы дубликатов осталась бы не первая строка ввода, а наименьшая
+func (s *Sorter) stable() bool {
+	return s.Stable || s.Unique
+}
+
+// Сортирует устойчиво, если нужно сохранить порядок строк с равными ключами
+func sortSlice[E any](x []E, stable bool, cmp func(a, b E) int) {
+	if stable {
+		slices.SortStableFunc(x, cmp)
+		return
+	}
+	slices.SortFunc(x, cmp)
+}
+
+// Сравнение последней надежды, как в GNU sort: если ключи равны, строки
+// сравниваются целиком побайтово. В устойчивом режиме строки остаются равными
+func (s *Sorter) lastResort(a, b string) int {
+	if s.stable() {
+		return 0
+	}
+	return compareStrings(a, b, s.Reverse)
+}
+
+// Функция сравнения для sortableLine, следующие ключи разрешают равенство предыдущих
+func compareLinesA(specs []KeySpec, a, b sortableLine) int {
+	for i := range specs {
+		if c := compareKeys(&specs[i], &a.keys[i], &b.keys[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Функция сравнения двух строк по заданным параметрам
+func (s *Sorter) compareLinesB(a, b string) int {
+	if s.err != nil {
+		return 0
+	}
+
+	// Ключи достаем прямо во время сравнения
+	c, err := s.compareByKeys(s.keys(), a, b)
+	if err != nil {
+		s.err = err
+		return 0
+	}
+	if c != 0 {
+		return c
+	}
+
+	return s.lastResort(a, b)
+}
+
+// Проверяет, отсортирован ли массив строк по тем же правилам, что и Check
+func (s *Sorter) isSorted(lines []string) bool {
+	s.err = nil
+	for i := 1; i < len(lines); i++ {
+		if ok, err := s.inOrder(lines[i-1], lines[i]); !ok || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Сравнивает строки с флагами
+func compareStrings(va string, vb string, reverse bool) int {
+	if reverse { //Если реверс
+		return strings.Compare(vb, va)
+	}
+	return strings.Compare(va, vb)
+}
+
+// Сравнение чисел с реверсом
+func compareInts(a, b int, reverse bool) int {
+	if a == b {
+		return 0
+	}
+	if reverse {
+		if a < b {
+			return 1
+		}
+		return -1
+	}
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+// Урезает хвостовые пробелы если надо
+// func cleanTrailingBlanks(lines []string) []string {
+// 	cleaned := make([]string, 0, len(lines))
+// 	for _, l := range lines {
+// 		s := strings.TrimRight(l, " \t") // убираем и хвостовые, и ведущие пробелы
+// 		cleaned = append(cleaned, s)
+// 	}
+// 	return cleaned
+// }
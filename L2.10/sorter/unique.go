@@ -0,0 +1,151 @@
+package sorter
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// UniquePolicy — какая строка из группы с равными ключами остается при -u
+type UniquePolicy int
+
+const (
+	KeepFirst UniquePolicy = iota // первая по порядку ввода, как в GNU sort
+	KeepLast                      // последняя по порядку ввода
+	KeepBest                      // первая по дополнительным ключам UniqueBy
+)
+
+// ParseUniquePolicy разбирает политику -u: first, last или ключ в формате -k, например 3,3nr.
+// Столбец по имени пишется с двоеточием, чтобы не спутать его с first и last: price:, price:nr
+func ParseUniquePolicy(value string) (UniquePolicy, []KeySpec, error) {
+	switch value {
+	case "first":
+		return KeepFirst, nil, nil
+	case "last":
+		return KeepLast, nil, nil
+	}
+
+	var keys []KeySpec
+	for spec := range strings.SplitSeq(value, " ") {
+		if spec == "" {
+			continue
+		}
+		k, err := ParseKeySpec(spec)
+		if err == nil && k.StartName != "" && !strings.Contains(spec, ":") {
+			err = fmt.Errorf("%w '%s'", ErrInvalidKey, spec)
+		}
+		if err != nil {
+			return KeepFirst, nil, errorf(msgUniquePolicy, err)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return KeepFirst, nil, errorf(msgEmptyUniquePolicy, ErrInvalidOption)
+	}
+	return KeepBest, keys, nil
+}
+
+// Куда пишутся готовые строки результата
+type lineSink interface {
+	WriteLine(line string) error
+	Close() error // дописывает то, что накоплено, но не сбрасывает буфер вывода
+}
+
+// Создает приемник строк, завершающий каждую строку eol.
+// При Unique он схлопывает группы с равными ключами, в CSV заново расставляет кавычки.
+// При Shuffle дубликаты уже схлопнуты до перемешивания
+func (s *Sorter) newLineSink(w *bufio.Writer, eol string) lineSink {
+	plain := plainSink{w, eol}
+	if !s.Unique || s.Shuffle {
+		return s.requoting(plain)
+	}
+	return s.requoting(&uniqueSink{s: s, out: plain})
+}
+
+// В CSV оборачивает приемник так, что кавычки ставятся только там, где нужны
+func (s *Sorter) requoting(out lineSink) lineSink {
+	if !s.CSV {
+		return out
+	}
+	return csvSink{out: out, fs: s.splitter()}
+}
+
+// Пишет строки как есть
+type plainSink struct {
+	w   *bufio.Writer
+	eol string // окончание строки
+}
+
+func (p plainSink) WriteLine(line string) error {
+	if _, err := p.w.WriteString(line); err != nil {
+		return err
+	}
+	_, err := p.w.WriteString(p.eol)
+	return err
+}
+
+func (p plainSink) Close() error { return nil }
+
+// Получает отсортированные строки и оставляет по одной на группу равных ключей.
+// В памяти держит только текущую группу: первую и выбранную строки
+type uniqueSink struct {
+	s     *Sorter
+	out   lineSink
+	first string // первая строка группы, с ней сравниваются ключи следующих
+	kept  string // строка, которая останется от группы
+	count int    // сколько строк в группе
+}
+
+func (u *uniqueSink) WriteLine(line string) error {
+	if u.count > 0 {
+		c, err := u.s.compareByKeys(u.s.keys(), u.first, line)
+		if err != nil {
+			return err
+		}
+		if c == 0 { // Та же группа
+			u.count++
+			return u.keep(line)
+		}
+		if err := u.flushGroup(); err != nil {
+			return err
+		}
+	}
+
+	u.first, u.kept, u.count = line, line, 1
+	return nil
+}
+
+// Решает, заменяет ли line выбранную строку группы
+func (u *uniqueSink) keep(line string) error {
+	switch u.s.UniqueKeep {
+	case KeepLast:
+		u.kept = line
+	case KeepBest:
+		c, err := u.s.compareByKeys(u.s.UniqueBy, line, u.kept)
+		if err != nil {
+			return err
+		}
+		if c < 0 {
+			u.kept = line
+		}
+	}
+	return nil
+}
+
+// Пишет выбранную строку группы, при CountDuplicates с числом повторов как uniq -c
+func (u *uniqueSink) flushGroup() error {
+	if u.s.CountDuplicates {
+		return u.out.WriteLine(fmt.Sprintf("%7d %s", u.count, u.kept))
+	}
+	return u.out.WriteLine(u.kept)
+}
+
+func (u *uniqueSink) Close() error {
+	if u.count > 0 {
+		if err := u.flushGroup(); err != nil {
+			return err
+		}
+		u.count, u.first = 0, ""
+	}
+	return u.out.Close()
+}
@@ -0,0 +1,90 @@
+package sorter
+
+import "strings"
+
+// Сравнение версий для -V в духе dpkg: строка делится на серии цифр и не-цифр,
+// цифры сравниваются по значению, ~ меньше всего, даже конца строки (1.0~rc1 < 1.0).
+// Эпоха вида 2: в начале сравнивается первой, отсутствующая эпоха равна 0
+func compareVersions(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if c := compareDigitRuns(epochA, epochB); c != 0 {
+		return c
+	}
+	return verrevcmp(restA, restB)
+}
+
+// Отделяет эпоху: 1:2.0 -> 1, 2.0
+func splitEpoch(v string) (epoch, rest string) {
+	i := 0
+	for i < len(v) && isDigit(v[i]) {
+		i++
+	}
+	if i > 0 && i < len(v) && v[i] == ':' {
+		return v[:i], v[i+1:]
+	}
+	return "", v
+}
+
+// Алгоритм сравнения версий из dpkg
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		// Не-цифровая часть
+		for i < len(a) && !isDigit(a[i]) || j < len(b) && !isDigit(b[j]) {
+			ac, bc := versionOrder(a, i), versionOrder(b, j)
+			if ac != bc {
+				return compareInts(ac, bc, false)
+			}
+			i++
+			j++
+		}
+
+		// Цифровая часть
+		startA, startB := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		if c := compareDigitRuns(a[startA:i], b[startB:j]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Вес символа версии: конец строки и цифры — 0, ~ — меньше всех,
+// буквы идут раньше остальных символов
+func versionOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+// Сравнивает серии цифр по значению без перевода в число, поэтому длина не ограничена
+func compareDigitRuns(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInts(len(a), len(b), false)
+	}
+	return strings.Compare(a, b)
+}
+
+// Проверяет, что байт — ASCII цифра
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}